@@ -0,0 +1,140 @@
+package main
+
+import "math"
+
+// animationFPS is the frame rate the Noise tab plays and exports at.
+const animationFPS = 25
+
+// noise3D and noise4D are noise with more dimensions, which animations
+// use for time: Perlin noise has a third one, and OpenSimplex2 has both, in
+// simplex noise.
+type noise3D interface {
+	Noise3D(x, y, z float64) float64
+}
+
+type noise4D interface {
+	Noise4D(x, y, z, w float64) float64
+}
+
+func (n openSimplex2) Noise3D(x, y, z float64) float64 {
+	return simplex4D(n.seed, x, y, z, 0)
+}
+
+func (n openSimplex2) Noise4D(x, y, z, w float64) float64 {
+	return simplex4D(n.seed, x, y, z, w)
+}
+
+// animate returns g at time t. If period isn't 0 the animation loops,
+// frame period being frame 0 again: 4D noise goes around a circle, and 3D
+// noise, with no fourth dimension to circle through, blends slice
+// t + period into slice t. Noise without a third dimension moves between
+// keyframes, each a different part of the plane, one time unit apart.
+func animate(g noiseGenerator, t, period float64) noiseGenerator {
+	if period != 0 {
+		t -= period * math.Floor(t/period)
+	}
+
+	if n, ok := g.(noise4D); ok && period != 0 {
+		r := period / (2 * math.Pi)
+		a := 2 * math.Pi * t / period
+		return loopSlice{n, r * math.Cos(a), r * math.Sin(a)}
+	}
+
+	if n, ok := g.(noise3D); ok {
+		if period == 0 {
+			return timeSlice{n, t}
+		}
+		return timeBlend{timeSlice{n, t + period}, timeSlice{n, t}, t / period}
+	}
+
+	k := math.Floor(t)
+	s := t - k
+	s = s * s * (3 - 2*s)
+
+	// keyframes repeat after the period, rounded to a whole number of them
+	var keys int64
+	if period != 0 {
+		keys = max(1, int64(math.Round(period)))
+	}
+	key := func(i int64) noiseGenerator {
+		i = wrap(i, keys)
+		return offsetNoise{g, 7919.3 * float64(i), -3571.7 * float64(i)}
+	}
+
+	return timeBlend{key(int64(k)), key(int64(k) + 1), s}
+}
+
+// timeSlice is the plane of 3D noise at z = t.
+type timeSlice struct {
+	n noise3D
+	t float64
+}
+
+func (s timeSlice) Noise2D(x, y float64) float64 {
+	return s.n.Noise3D(x, y, s.t)
+}
+
+// loopSlice is the plane of 4D noise at z, w, which an animation turns
+// around a circle.
+type loopSlice struct {
+	n    noise4D
+	z, w float64
+}
+
+func (s loopSlice) Noise2D(x, y float64) float64 {
+	return s.n.Noise4D(x, y, s.z, s.w)
+}
+
+// offsetNoise is base moved by ox, oy.
+type offsetNoise struct {
+	base   noiseGenerator
+	ox, oy float64
+}
+
+func (o offsetNoise) Noise2D(x, y float64) float64 {
+	return o.base.Noise2D(x+o.ox, y+o.oy)
+}
+
+// tile repeats base, which repeats moved as well.
+func (o offsetNoise) tile(px, py float64) noiseGenerator {
+	t, ok := o.base.(tileable)
+	if !ok {
+		return nil
+	}
+	r := t.tile(px, py)
+	if r == nil {
+		return nil
+	}
+	o.base = r
+
+	return o
+}
+
+// timeBlend mixes a and b, s of the way between them, scaled back to the
+// contrast the mix loses halfway.
+type timeBlend struct {
+	a, b noiseGenerator
+	s    float64
+}
+
+func (b timeBlend) Noise2D(x, y float64) float64 {
+	return ((1-b.s)*b.a.Noise2D(x, y) + b.s*b.b.Noise2D(x, y)) / math.Sqrt((1-b.s)*(1-b.s)+b.s*b.s)
+}
+
+// tile repeats both a and b, if they can.
+func (b timeBlend) tile(px, py float64) noiseGenerator {
+	ta, ok := b.a.(tileable)
+	if !ok {
+		return nil
+	}
+	tb, ok := b.b.(tileable)
+	if !ok {
+		return nil
+	}
+	b.a, b.b = ta.tile(px, py), tb.tile(px, py)
+	if b.a == nil || b.b == nil {
+		return nil
+	}
+
+	return b
+}
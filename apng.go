@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+// apngEncoder writes an animated PNG frame by frame, each encoded by
+// image/png, whose IDAT chunks become the fdAT chunks of the animation.
+type apngEncoder struct {
+	w      io.Writer
+	frames int
+
+	// delay is how long each frame shows, as a fraction.
+	delayNum, delayDen uint16
+
+	seq  uint32
+	n    int
+	ihdr []byte
+}
+
+// newAPNGEncoder starts an animation of frames frames, looping forever.
+func newAPNGEncoder(w io.Writer, frames, fps int) *apngEncoder {
+	return &apngEncoder{w: w, frames: frames, delayNum: 1, delayDen: uint16(fps)}
+}
+
+func (e *apngEncoder) chunk(typ string, data []byte) error {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.WriteString(typ)
+	b.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(b.Bytes()[4:])
+	binary.Write(&b, binary.BigEndian, crc.Sum32())
+
+	_, err := e.w.Write(b.Bytes())
+	return err
+}
+
+// frame adds img, which must be as large and encode to the same kind of
+// PNG as the first frame.
+func (e *apngEncoder) frame(img image.Image) error {
+	if e.n >= e.frames {
+		return errors.New("apng: too many frames")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// the chunks after the signature: length, type, data and CRC
+	data := buf.Bytes()[8:]
+	var idat [][]byte
+	var ihdr []byte
+	for len(data) >= 12 {
+		l := binary.BigEndian.Uint32(data)
+		typ, body := string(data[4:8]), data[8:8+l]
+		switch typ {
+		case "IHDR":
+			ihdr = body
+		case "IDAT":
+			idat = append(idat, body)
+		}
+		data = data[12+l:]
+	}
+
+	if e.n == 0 {
+		e.ihdr = ihdr
+		if _, err := e.w.Write(buf.Bytes()[:8]); err != nil {
+			return err
+		}
+		if err := e.chunk("IHDR", ihdr); err != nil {
+			return err
+		}
+
+		// acTL: the number of frames, and 0 to loop forever
+		actl := make([]byte, 8)
+		binary.BigEndian.PutUint32(actl, uint32(e.frames))
+		if err := e.chunk("acTL", actl); err != nil {
+			return err
+		}
+	} else if !bytes.Equal(ihdr, e.ihdr) {
+		return errors.New("apng: frames differ in size or color type")
+	}
+
+	// fcTL: the whole image, replacing the frame before
+	b := img.Bounds()
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], e.seq)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(b.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(b.Dy()))
+	binary.BigEndian.PutUint16(fctl[20:], e.delayNum)
+	binary.BigEndian.PutUint16(fctl[22:], e.delayDen)
+	e.seq++
+	if err := e.chunk("fcTL", fctl); err != nil {
+		return err
+	}
+
+	for _, d := range idat {
+		if e.n == 0 {
+			if err := e.chunk("IDAT", d); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fdat := make([]byte, 4+len(d))
+		binary.BigEndian.PutUint32(fdat, e.seq)
+		copy(fdat[4:], d)
+		e.seq++
+		if err := e.chunk("fdAT", fdat); err != nil {
+			return err
+		}
+	}
+	e.n++
+
+	if e.n == e.frames {
+		return e.chunk("IEND", nil)
+	}
+
+	return nil
+}
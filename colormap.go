@@ -0,0 +1,50 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// colormap maps [0, 1] to colors by interpolating evenly spaced stops.
+type colormap []color.RGBA
+
+func hex(v uint32) color.RGBA {
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+var colormaps = map[string]colormap{
+	"Viridis": {
+		hex(0x440154), hex(0x472d7b), hex(0x3b528b), hex(0x2c728e), hex(0x21918c),
+		hex(0x28ae80), hex(0x5ec962), hex(0xaddc30), hex(0xfde725),
+	},
+	"Magma": {
+		hex(0x000004), hex(0x1c1044), hex(0x4f127b), hex(0x812581), hex(0xb5367a),
+		hex(0xe55064), hex(0xfb8761), hex(0xfec287), hex(0xfcfdbf),
+	},
+	"Cividis": {
+		hex(0x00224e), hex(0x123570), hex(0x3b496c), hex(0x575d6d), hex(0x707173),
+		hex(0x8a8779), hex(0xa69d75), hex(0xc4b56c), hex(0xfee838),
+	},
+	"Diverging": {
+		hex(0x3b4cc0), hex(0x5977e3), hex(0x7b9ff9), hex(0x9ebeff), hex(0xdddddd),
+		hex(0xf2cbb7), hex(0xf7ac8e), hex(0xee8468), hex(0xb40426),
+	},
+}
+
+var colormapNames = []string{"Viridis", "Magma", "Cividis", "Diverging"}
+
+func (c colormap) at(t float64) color.RGBA {
+	if math.IsNaN(t) {
+		return color.RGBA{}
+	}
+
+	t = math.Max(0, math.Min(1, t)) * float64(len(c)-1)
+	i := min(int(t), len(c)-2)
+	f := t - float64(i)
+
+	lerp := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*f + 0.5)
+	}
+
+	return color.RGBA{lerp(c[i].R, c[i+1].R), lerp(c[i].G, c[i+1].G), lerp(c[i].B, c[i+1].B), 0xff}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/cmplx"
+	"strings"
+)
+
+// complexMode evaluates equations with complex arithmetic, plotting the
+// points where they are real.
+var complexMode = false
+
+type complexVars struct {
+	x, y, z complex128
+}
+
+type complexFunc func(v *complexVars) complex128
+
+var complexConstants = map[string]complex128{
+	"i":  1i,
+	"e":  math.E,
+	"π":  math.Pi,
+	"pi": math.Pi,
+}
+
+var complexFunctions = map[string]func(complex128) complex128{
+	"re":    func(z complex128) complex128 { return complex(real(z), 0) },
+	"im":    func(z complex128) complex128 { return complex(imag(z), 0) },
+	"arg":   func(z complex128) complex128 { return complex(cmplx.Phase(z), 0) },
+	"abs":   func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) },
+	"conj":  cmplx.Conj,
+	"exp":   cmplx.Exp,
+	"log":   cmplx.Log,
+	"log10": cmplx.Log10,
+	"sqrt":  cmplx.Sqrt,
+	"sin":   cmplx.Sin,
+	"cos":   cmplx.Cos,
+	"tan":   cmplx.Tan,
+	"asin":  cmplx.Asin,
+	"acos":  cmplx.Acos,
+	"atan":  cmplx.Atan,
+	"sinh":  cmplx.Sinh,
+	"cosh":  cmplx.Cosh,
+	"tanh":  cmplx.Tanh,
+	"asinh": cmplx.Asinh,
+	"acosh": cmplx.Acosh,
+	"atanh": cmplx.Atanh,
+}
+
+var complex2Functions = map[string]func(a, b complex128) complex128{
+	"pow": cmplx.Pow,
+	"log": func(z, base complex128) complex128 { return cmplx.Log(z) / cmplx.Log(base) },
+}
+
+// compileComplex turns an expression into a function of x, y and z using
+// complex arithmetic.
+func compileComplex(n *node) (complexFunc, error) {
+	args := make([]complexFunc, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], err = compileComplex(a); err != nil {
+			return nil, err
+		}
+	}
+
+	switch n.op {
+	case "num":
+		c := complex(n.value, 0)
+		return func(*complexVars) complex128 { return c }, nil
+	case "var":
+		switch n.name {
+		case "x":
+			return func(v *complexVars) complex128 { return v.x }, nil
+		case "y":
+			return func(v *complexVars) complex128 { return v.y }, nil
+		case "z":
+			return func(v *complexVars) complex128 { return v.z }, nil
+		}
+		if c, ok := complexConstants[n.name]; ok {
+			return func(*complexVars) complex128 { return c }, nil
+		}
+
+		return nil, fmt.Errorf("unknown variable '%s'", n.name)
+	case "neg":
+		a := args[0]
+		return func(v *complexVars) complex128 { return -a(v) }, nil
+	case "+":
+		a, b := args[0], args[1]
+		return func(v *complexVars) complex128 { return a(v) + b(v) }, nil
+	case "-":
+		a, b := args[0], args[1]
+		return func(v *complexVars) complex128 { return a(v) - b(v) }, nil
+	case "*":
+		a, b := args[0], args[1]
+		return func(v *complexVars) complex128 { return a(v) * b(v) }, nil
+	case "/":
+		a, b := args[0], args[1]
+		return func(v *complexVars) complex128 { return a(v) / b(v) }, nil
+	case "^":
+		a, b := args[0], args[1]
+		return func(v *complexVars) complex128 { return cmplx.Pow(a(v), b(v)) }, nil
+	case "call":
+		if f, ok := complexFunctions[n.name]; ok && len(args) == 1 {
+			a := args[0]
+			return func(v *complexVars) complex128 { return f(a(v)) }, nil
+		}
+		if f, ok := complex2Functions[n.name]; ok && len(args) == 2 {
+			a, b := args[0], args[1]
+			return func(v *complexVars) complex128 { return f(a(v), b(v)) }, nil
+		}
+
+		return nil, fmt.Errorf("unknown complex function '%s' of %d arguments", n.name, len(args))
+	}
+
+	return nil, fmt.Errorf("'%s' isn't defined on complex numbers", n.op)
+}
+
+func parseComplex(str string) (complexFunc, error) {
+	n, err := parseExpr(str)
+	if err != nil {
+		return nil, err
+	}
+
+	return compileComplex(n)
+}
+
+// parseComplexGraph is parseMultiequationGraph in complex mode, only
+// keeping the values that are real.
+func parseComplexGraph(str string) (Graph, error) {
+	s := parseMultiequation(str)
+
+	var fs [2][]complexFunc
+	for i := range s {
+		for _, eq := range s[i] {
+			f, err := parseComplex(eq)
+			if err != nil {
+				return nil, err
+			}
+			fs[i] = append(fs[i], f)
+		}
+	}
+
+	return func(x, y float64) (x1, y1 []float64) {
+		v := &complexVars{x: complex(x, 0), y: complex(y, 0), z: complex(x, y)}
+
+		for _, f := range fs[0] {
+			if r, ok := realValue(f(v)); ok {
+				x1 = append(x1, r)
+			}
+		}
+		for _, f := range fs[1] {
+			if r, ok := realValue(f(v)); ok {
+				y1 = append(y1, r)
+			}
+		}
+
+		return
+	}, nil
+}
+
+func realValue(c complex128) (float64, bool) {
+	return real(c), math.Abs(imag(c)) <= 1e-9*(1+math.Abs(real(c)))
+}
+
+// domainColoring plots w = f(z) by coloring every point z of the plane
+// with the hue of arg(w) and the lightness of |w|.
+type domainColoring struct {
+	f complexFunc
+
+	// contours darkens bands between powers of two of |w|.
+	contours bool
+}
+
+func parseDomainColoring(str string) (*domainColoring, error) {
+	lhs, str, ok := strings.Cut(str, "=")
+	if !ok || strings.TrimSpace(lhs) != "w" {
+		return nil, fmt.Errorf("expected w = f(z)")
+	}
+
+	f, err := parseComplex(str)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainColoring{f: f}, nil
+}
+
+func (d *domainColoring) draw() {
+	b := graph.Rect
+	var v complexVars
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			x, y := fromPixel(float64(px), float64(py))
+			v.x, v.y, v.z = complex(x, 0), complex(y, 0), complex(x, y)
+
+			w := d.f(&v)
+			if cmplx.IsNaN(w) {
+				continue
+			}
+
+			hue := cmplx.Phase(w) / (2 * math.Pi)
+			if hue < 0 {
+				hue++
+			}
+
+			m := cmplx.Abs(w)
+			lightness := 2 / math.Pi * math.Atan(m)
+			if d.contours && m != 0 && !math.IsInf(m, 0) {
+				_, frac := math.Modf(math.Log2(m))
+				if frac < 0 {
+					frac++
+				}
+				lightness *= 0.7 + 0.3*frac
+			}
+
+			graph.Set(px, py, hsl(hue, 1, lightness))
+		}
+	}
+}
+
+// hsl converts a color from hue, saturation and lightness in [0, 1].
+func hsl(h, s, l float64) color.RGBA64 {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h * 6
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+
+	var r, g, b float64
+	switch int(hp) % 6 {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	case 5:
+		r, b = c, x
+	}
+
+	m := l - c/2
+	return color.RGBA64{
+		R: uint16((r + m) * 0xffff),
+		G: uint16((g + m) * 0xffff),
+		B: uint16((b + m) * 0xffff),
+		A: 0xffff,
+	}
+}
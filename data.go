@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// dataset is a list of points imported from a CSV/TSV file, plotted with
+// markers and usable in expressions as name.column.
+type dataset struct {
+	name    string
+	path    string
+	delim   rune
+	header  bool
+	columns []string
+	values  [][]float64
+
+	xCol, yCol int
+
+	markers bool
+	lines   bool
+
+	// version counts reloads, so that fits know when to rerun.
+	version int
+}
+
+var datasets = make(map[string]*dataset)
+
+var delimiters = map[string]rune{
+	"Comma":     ',',
+	"Semicolon": ';',
+	"Tab":       '\t',
+	"Space":     ' ',
+}
+
+// detectDelimiter guesses the delimiter of a line of delimited text.
+func detectDelimiter(line string) rune {
+	for _, d := range []rune{'\t', ';', ','} {
+		if strings.ContainsRune(line, d) {
+			return d
+		}
+	}
+
+	return ' '
+}
+
+// detectHeader reports whether a record looks like a header, that is
+// whether any of its fields is not a number.
+func detectHeader(record []string) bool {
+	for _, f := range record {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(f), 64); err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readRecords reads every record of a delimited file. A delimiter of 0 is
+// detected from the first line.
+func readRecords(path string, delim rune) ([][]string, rune, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, delim, err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	if delim == 0 {
+		line, _ := br.Peek(4096)
+		first, _, _ := strings.Cut(string(line), "\n")
+		delim = detectDelimiter(first)
+	}
+
+	if delim == ' ' {
+		var records [][]string
+		sc := bufio.NewScanner(br)
+		for sc.Scan() {
+			if f := strings.Fields(sc.Text()); len(f) != 0 {
+				records = append(records, f)
+			}
+		}
+
+		return records, delim, sc.Err()
+	}
+
+	r := csv.NewReader(br)
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+
+	return records, delim, err
+}
+
+var notIdentifier = regexp.MustCompile(`\W+`)
+
+// identifier turns s into a name usable in expressions.
+func identifier(s, fallback string) string {
+	s = strings.Trim(notIdentifier.ReplaceAllString(strings.TrimSpace(s), "_"), "_")
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return fallback
+	}
+
+	return s
+}
+
+// newDataset builds a dataset out of records, using the first record for
+// column names if header is set. Cells that aren't numbers become NaN.
+func newDataset(path string, records [][]string, header bool) *dataset {
+	d := &dataset{
+		path:    path,
+		header:  header,
+		yCol:    1,
+		markers: true,
+	}
+
+	base := filepath.Base(path)
+	d.name = identifier(strings.TrimSuffix(base, filepath.Ext(base)), "data")
+	for i := 2; datasets[d.name] != nil; i++ {
+		d.name = fmt.Sprintf("%s%d", strings.TrimRight(d.name, "0123456789"), i)
+	}
+
+	var n int
+	for _, r := range records {
+		n = max(n, len(r))
+	}
+
+	d.columns = make([]string, n)
+	d.values = make([][]float64, n)
+	for i := range d.columns {
+		d.columns[i] = fmt.Sprintf("c%d", i+1)
+	}
+
+	if header && len(records) != 0 {
+		for i, f := range records[0] {
+			d.columns[i] = identifier(f, d.columns[i])
+		}
+		records = records[1:]
+	}
+
+	for i := range d.values {
+		d.values[i] = make([]float64, len(records))
+		for j, r := range records {
+			d.values[i][j] = math.NaN()
+			if i < len(r) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(r[i]), 64); err == nil {
+					d.values[i][j] = v
+				}
+			}
+		}
+	}
+
+	if n < 2 {
+		d.yCol = 0
+	}
+
+	return d
+}
+
+// reload reads the dataset's file again, keeping its name and settings.
+func (d *dataset) reload() error {
+	records, _, err := readRecords(d.path, d.delim)
+	if err != nil {
+		return err
+	}
+
+	n := newDataset(d.path, records, d.header)
+	if len(n.columns) == 0 {
+		return fmt.Errorf("%s has no data", d.path)
+	}
+
+	d.columns, d.values = n.columns, n.values
+	d.xCol = min(d.xCol, len(d.columns)-1)
+	d.yCol = min(d.yCol, len(d.columns)-1)
+	d.version++
+
+	return nil
+}
+
+// column returns the values of the named column. x and y refer to the
+// plotted columns unless the data has columns of those names.
+func (d *dataset) column(name string) ([]float64, bool) {
+	for i, c := range d.columns {
+		if c == name {
+			return d.values[i], true
+		}
+	}
+
+	switch name {
+	case "x":
+		return d.values[d.xCol], true
+	case "y":
+		return d.values[d.yCol], true
+	}
+
+	return nil, false
+}
+
+// datasetColumn resolves a name.column reference to the column values.
+func datasetColumn(ref string) ([]float64, bool) {
+	name, col, ok := strings.Cut(ref, ".")
+	if !ok {
+		return nil, false
+	}
+
+	d, ok := datasets[name]
+	if !ok {
+		return nil, false
+	}
+
+	return d.column(col)
+}
+
+func (d *dataset) draw(c color.Color) {
+	xs, ys := d.values[d.xCol], d.values[d.yCol]
+
+	var lastX, lastY = math.NaN(), math.NaN()
+	for i := range xs {
+		px, py := toPixel(xs[i], ys[i])
+		if math.IsNaN(px) || math.IsNaN(py) {
+			lastX, lastY = math.NaN(), math.NaN()
+			continue
+		}
+
+		if d.markers {
+			drawMarker(px, py, c)
+		}
+		if d.lines && !math.IsNaN(lastX) {
+			drawLine(lastX, lastY, px, py, c)
+		}
+		lastX, lastY = px, py
+	}
+}
+
+func drawMarker(x, y float64, c color.Color) {
+	for dx := -3; dx <= 3; dx++ {
+		for dy := -3; dy <= 3; dy++ {
+			if dx*dx+dy*dy <= 9 {
+				graph.Set(int(x)+dx, int(y)+dy, c)
+			}
+		}
+	}
+}
+
+// drawLine draws a line between two image coordinates.
+func drawLine(x0, y0, x1, y1 float64, c color.Color) {
+	steps := math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0)))
+	if steps > 1e5 {
+		return
+	}
+
+	for i := 0.0; i <= steps; i++ {
+		t := i / math.Max(steps, 1)
+		x, y := x0+(x1-x0)*t, y0+(y1-y0)*t
+		graph.Set(int(x), int(y), c)
+		graph.Set(int(x+1), int(y), c)
+		graph.Set(int(x), int(y+1), c)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// node is an expression parsed by parseExpr, for the evaluation modes
+// govaluate can't handle since it only computes with float64.
+//
+// op is "num" for a literal, "var" for a variable, "call" for a function
+// call, "list" for a [a, b, …] literal, "neg" for negation and the
+// operator otherwise, with its operands in args.
+type node struct {
+	op    string
+	value float64
+	name  string
+	args  []*node
+}
+
+var exprOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "^": true, "**": true,
+	"<": true, "<=": true, ">": true, ">=": true, "==": true, "!=": true,
+	"!": true, "&&": true, "||": true, "?": true, ":": true,
+	"(": true, ")": true, ",": true, "[": true, "]": true,
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// tokenizeExpr splits an expression into numbers, names and operators.
+func tokenizeExpr(s string) ([]string, error) {
+	var tokens []string
+	r := []rune(s)
+
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.') {
+				j++
+			}
+			if j+1 < len(r) && (r[j] == 'e' || r[j] == 'E') && (unicode.IsDigit(r[j+1]) ||
+				(j+2 < len(r) && (r[j+1] == '-' || r[j+1] == '+') && unicode.IsDigit(r[j+2]))) {
+				j += 2
+				for j < len(r) && unicode.IsDigit(r[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_' || r[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		default:
+			op := string(c)
+			if i+1 < len(r) && exprOperators[string(r[i:i+2])] {
+				op = string(r[i : i+2])
+			}
+			if !exprOperators[op] {
+				return nil, fmt.Errorf("unexpected '%s'", op)
+			}
+			tokens = append(tokens, op)
+			i += len([]rune(op))
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseExpr parses s, accepting the same notation as the govaluate based
+// evaluation: ^ or ** for powers, the ternary ?: and comparisons, plus
+// implicit multiplication such as 2x or 3i.
+func parseExpr(s string) (*node, error) {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	n, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.pos])
+	}
+
+	return n, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	for _, op := range ops {
+		if t == op {
+			p.pos++
+			return t, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *exprParser) ternary() (*node, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+
+	a, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept(":"); !ok {
+		return nil, fmt.Errorf("expected ':'")
+	}
+	b, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &node{op: "?", args: []*node{cond, a, b}}, nil
+}
+
+// binaryLevels lists the binary operators from the loosest to the tightest
+// binding, all left associative. Powers are handled by power.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", "<=", ">", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (*node, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok && level == len(binaryLevels)-1 && p.startsOperand() {
+			op, ok = "*", true
+		}
+		if !ok {
+			return x, nil
+		}
+
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &node{op: op, args: []*node{x, y}}
+	}
+}
+
+// startsOperand reports whether the next token begins an operand, for
+// implicit multiplication.
+func (p *exprParser) startsOperand() bool {
+	t := p.peek()
+	if t == "(" || t == "[" {
+		return true
+	}
+
+	return t != "" && (unicode.IsLetter([]rune(t)[0]) || unicode.IsDigit([]rune(t)[0]) || t[0] == '.' || t[0] == '_')
+}
+
+func (p *exprParser) unary() (*node, error) {
+	if op, ok := p.accept("-", "+", "!"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "+":
+			return x, nil
+		case "-":
+			op = "neg"
+		}
+
+		return &node{op: op, args: []*node{x}}, nil
+	}
+
+	return p.power()
+}
+
+func (p *exprParser) power() (*node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.accept("^", "**"); ok {
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &node{op: "^", args: []*node{x, y}}, nil
+	}
+
+	return x, nil
+}
+
+func (p *exprParser) primary() (*node, error) {
+	t := p.peek()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		p.pos++
+		x, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("expected ')'")
+		}
+
+		return x, nil
+	case t == "[":
+		p.pos++
+		list := &node{op: "list"}
+		if _, ok := p.accept("]"); ok {
+			return list, nil
+		}
+		for {
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			list.args = append(list.args, x)
+
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			if _, ok := p.accept(","); !ok {
+				return nil, fmt.Errorf("expected ',' or ']'")
+			}
+		}
+	case unicode.IsDigit([]rune(t)[0]) || t[0] == '.':
+		p.pos++
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", t)
+		}
+
+		return &node{op: "num", value: v, name: t}, nil
+	case unicode.IsLetter([]rune(t)[0]) || t[0] == '_':
+		p.pos++
+		if _, ok := p.accept("("); !ok {
+			return &node{op: "var", name: t}, nil
+		}
+
+		call := &node{op: "call", name: t}
+		if _, ok := p.accept(")"); ok {
+			return call, nil
+		}
+		for {
+			arg, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+
+			if _, ok := p.accept(")"); ok {
+				return call, nil
+			}
+			if _, ok := p.accept(","); !ok {
+				return nil, fmt.Errorf("expected ',' or ')'")
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected '%s'", t)
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// fit is a model such as y ~ a*x^2 + b*x + c whose free parameters are
+// fitted against a dataset.
+type fit struct {
+	data  *dataset
+	yName string
+
+	model  *govaluate.EvaluableExpression
+	params []string
+
+	values []float64
+	errors []float64
+	r2     float64
+	linear bool
+
+	xs, residuals []float64
+
+	// version is the data version the fit was last run against.
+	version int
+	err     error
+
+	// onRun is called every time the fit is run.
+	onRun func()
+}
+
+// fitParams resolves the variables of a model for a set of parameter
+// values.
+type fitParams struct {
+	f      *fit
+	x      float64
+	values []float64
+}
+
+func (p fitParams) Get(name string) (interface{}, error) {
+	if i := slices.Index(p.f.params, name); i != -1 {
+		return p.values[i], nil
+	}
+
+	return graphParams{p.x, 0}.Get(name)
+}
+
+// parseFit parses a model of the form lhs ~ rhs. lhs names the data column
+// to fit, either as data.y or, when only one dataset is imported, as y.
+// References to the data's x column in rhs stand for x.
+func parseFit(str string) (*fit, error) {
+	lhs, rhs, ok := strings.Cut(str, "~")
+	if !ok {
+		return nil, fmt.Errorf("expected a model like y ~ a*x + b")
+	}
+
+	f := &fit{}
+	lhs = strings.TrimSpace(lhs)
+	if name, col, ok := strings.Cut(lhs, "."); ok {
+		f.data, f.yName = datasets[name], col
+		if f.data == nil {
+			return nil, fmt.Errorf("no data named '%s'", name)
+		}
+	} else {
+		if len(datasets) != 1 {
+			return nil, fmt.Errorf("name the data to fit, as in data.%s ~ ...", lhs)
+		}
+		for _, d := range datasets {
+			f.data, f.yName = d, lhs
+		}
+	}
+
+	if _, ok := f.data.column(f.yName); !ok {
+		return nil, fmt.Errorf("%s has no column '%s'", f.data.name, f.yName)
+	}
+
+	xRef := regexp.MustCompile(regexp.QuoteMeta(f.data.name) + `\.(x|` + regexp.QuoteMeta(f.data.columns[f.data.xCol]) + `)\b`)
+	rhs = xRef.ReplaceAllString(rhs, "x")
+
+	var err error
+	f.model, err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(rhs), functions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range f.model.Vars() {
+		if _, err := (graphParams{}).Get(v); err != nil && !slices.Contains(f.params, v) {
+			f.params = append(f.params, v)
+		}
+	}
+	if len(f.params) == 0 {
+		return nil, fmt.Errorf("the model has no parameters to fit")
+	}
+
+	return f, nil
+}
+
+func (f *fit) eval(x float64, values []float64) float64 {
+	v, err := f.model.Eval(fitParams{f, x, values})
+	if r, ok := v.(float64); ok && err == nil {
+		return r
+	}
+
+	return math.NaN()
+}
+
+func (f *fit) predict(xs, values []float64) []float64 {
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = f.eval(x, values)
+	}
+
+	return ys
+}
+
+// jacobian returns the derivatives of the model at every x with respect to
+// every parameter, by central differences.
+func (f *fit) jacobian(xs, values []float64) [][]float64 {
+	j := make([][]float64, len(xs))
+	for i := range j {
+		j[i] = make([]float64, len(values))
+	}
+
+	v := slices.Clone(values)
+	for k := range values {
+		h := 1e-6 * math.Max(1, math.Abs(values[k]))
+
+		v[k] = values[k] + h
+		up := f.predict(xs, v)
+		v[k] = values[k] - h
+		down := f.predict(xs, v)
+		v[k] = values[k]
+
+		for i := range xs {
+			j[i][k] = (up[i] - down[i]) / (2 * h)
+		}
+	}
+
+	return j
+}
+
+func sumOfSquares(ys, predicted []float64) float64 {
+	var sum float64
+	for i := range ys {
+		sum += (ys[i] - predicted[i]) * (ys[i] - predicted[i])
+	}
+
+	return sum
+}
+
+// isLinear reports whether the model is linear in its parameters, that is
+// whether it equals c + J·p, where c is the model at p = 0 and J's columns
+// are the model at each unit vector minus c.
+func (f *fit) isLinear(xs []float64) bool {
+	p := len(f.params)
+	c := f.predict(xs, make([]float64, p))
+
+	probe := make([]float64, p)
+	for k := range probe {
+		probe[k] = 0.7 + 0.37*float64(k)
+	}
+	expected := slices.Clone(c)
+
+	unit := make([]float64, p)
+	for k := range unit {
+		unit[k] = 1
+		col := f.predict(xs, unit)
+		unit[k] = 0
+
+		for i := range col {
+			expected[i] += (col[i] - c[i]) * probe[k]
+		}
+	}
+
+	for i, y := range f.predict(xs, probe) {
+		if math.IsNaN(y) || math.IsNaN(expected[i]) || math.Abs(y-expected[i]) > 1e-8*(1+math.Abs(y)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fitLinear solves the linear least squares problem (JᵀJ)·p = Jᵀ(y - c).
+func (f *fit) fitLinear(xs, ys []float64) ([]float64, error) {
+	p := len(f.params)
+	c := f.predict(xs, make([]float64, p))
+
+	r := make([]float64, len(ys))
+	for i := range ys {
+		r[i] = ys[i] - c[i]
+	}
+
+	jtj, jtr := normalEquations(f.jacobian(xs, make([]float64, p)), r)
+
+	return solve(jtj, jtr)
+}
+
+// fitLevenbergMarquardt minimises the sum of squared residuals starting
+// from every parameter at 1.
+func (f *fit) fitLevenbergMarquardt(xs, ys []float64) ([]float64, error) {
+	values := make([]float64, len(f.params))
+	for k := range values {
+		values[k] = 1
+	}
+
+	cost := sumOfSquares(ys, f.predict(xs, values))
+	if math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return nil, fmt.Errorf("the model can't be evaluated with every parameter at 1")
+	}
+
+	lambda := 1e-3
+	for iter := 0; iter < 200; iter++ {
+		predicted := f.predict(xs, values)
+		r := make([]float64, len(ys))
+		for i := range ys {
+			r[i] = ys[i] - predicted[i]
+		}
+		jtj, jtr := normalEquations(f.jacobian(xs, values), r)
+
+		improved := false
+		for tries := 0; tries < 20 && !improved; tries++ {
+			a := make([][]float64, len(jtj))
+			for k := range jtj {
+				a[k] = slices.Clone(jtj[k])
+				a[k][k] += lambda * math.Max(jtj[k][k], 1e-12)
+			}
+
+			delta, err := solve(a, jtr)
+			if err != nil {
+				lambda *= 10
+				continue
+			}
+
+			next := slices.Clone(values)
+			for k := range next {
+				next[k] += delta[k]
+			}
+
+			if c := sumOfSquares(ys, f.predict(xs, next)); c < cost {
+				improved = cost-c > 1e-12*cost
+				values, cost = next, c
+				lambda = math.Max(lambda/10, 1e-12)
+				if !improved {
+					return values, nil
+				}
+			} else {
+				lambda *= 10
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	return values, nil
+}
+
+// run fits the model against the current data.
+func (f *fit) run() error {
+	f.version = f.data.version
+	f.err = f.fit()
+	if f.err != nil {
+		f.values = nil
+	}
+
+	if f.onRun != nil {
+		f.onRun()
+	}
+
+	return f.err
+}
+
+func (f *fit) fit() error {
+	dataX, ok := f.data.column("x")
+	if !ok {
+		return fmt.Errorf("no column %q", "x")
+	}
+	dataY, ok := f.data.column(f.yName)
+	if !ok {
+		return fmt.Errorf("no column %q", f.yName)
+	}
+
+	var xs, ys []float64
+	for i := range dataX {
+		if !math.IsNaN(dataX[i]) && !math.IsNaN(dataY[i]) {
+			xs = append(xs, dataX[i])
+			ys = append(ys, dataY[i])
+		}
+	}
+
+	n, p := len(xs), len(f.params)
+	if n < p {
+		return fmt.Errorf("need at least %d points to fit %d parameters", p, p)
+	}
+
+	var err error
+	f.linear = f.isLinear(xs)
+	if f.linear {
+		f.values, err = f.fitLinear(xs, ys)
+	} else {
+		f.values, err = f.fitLevenbergMarquardt(xs, ys)
+	}
+	if err != nil {
+		return err
+	}
+
+	predicted := f.predict(xs, f.values)
+	f.xs = xs
+	f.residuals = make([]float64, n)
+	for i := range ys {
+		f.residuals[i] = ys[i] - predicted[i]
+	}
+
+	var mean float64
+	for _, y := range ys {
+		mean += y / float64(n)
+	}
+	ssr := sumOfSquares(ys, predicted)
+	var sst float64
+	for _, y := range ys {
+		sst += (y - mean) * (y - mean)
+	}
+	f.r2 = 1 - ssr/sst
+
+	f.errors = make([]float64, p)
+	jtj, _ := normalEquations(f.jacobian(xs, f.values), make([]float64, n))
+	cov, err := inverse(jtj)
+	for k := range f.errors {
+		f.errors[k] = math.NaN()
+		if err == nil && n > p {
+			f.errors[k] = math.Sqrt(ssr / float64(n-p) * cov[k][k])
+		}
+	}
+
+	return nil
+}
+
+// graph returns the fitted curve, which follows the fit as it is rerun.
+func (f *fit) graph() Graph {
+	return func(x, y float64) (x1, y1 []float64) {
+		if f.values == nil {
+			return nil, nil
+		}
+
+		return oneXandOneY(x, f.eval(x, f.values))
+	}
+}
+
+func (f *fit) summary() string {
+	if f.err != nil {
+		return f.err.Error()
+	}
+
+	var s []string
+	for k, name := range f.params {
+		s = append(s, fmt.Sprintf("%s = %.4g", name, f.values[k]))
+	}
+
+	return fmt.Sprintf("%s, R² = %.4f", strings.Join(s, ", "), f.r2)
+}
+
+func (f *fit) details() string {
+	if f.err != nil {
+		return f.err.Error()
+	}
+
+	method := "Levenberg-Marquardt"
+	if f.linear {
+		method = "Linear least squares"
+	}
+
+	s := []string{fmt.Sprintf("%s on %d points of %s", method, len(f.xs), f.data.name)}
+	for k, name := range f.params {
+		s = append(s, fmt.Sprintf("%s = %.6g ± %.3g", name, f.values[k], f.errors[k]))
+	}
+	s = append(s, fmt.Sprintf("R² = %.6f", f.r2))
+
+	return strings.Join(s, "\n")
+}
+
+// residualPlot draws the residuals against x.
+func (f *fit) residualPlot(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if len(f.xs) == 0 {
+		return img
+	}
+
+	minX, maxX := slices.Min(f.xs), slices.Max(f.xs)
+	var maxR float64
+	for _, r := range f.residuals {
+		maxR = math.Max(maxR, math.Abs(r))
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	if maxR == 0 {
+		maxR = 1
+	}
+
+	for x := 0; x < w; x++ {
+		img.Set(x, h/2, color.Gray{0x80})
+	}
+
+	for i, x := range f.xs {
+		px := 4 + int((x-minX)/(maxX-minX)*float64(w-9))
+		py := h/2 - int(f.residuals[i]/maxR*float64(h/2-4))
+		for dx := -2; dx <= 2; dx++ {
+			for dy := -2; dy <= 2; dy++ {
+				img.Set(px+dx, py+dy, palette[5])
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// fractalModes are the ways fractalNoise layers its octaves.
+var fractalModes = []string{"None", "fBm", "Ridged", "Billow", "Turbulence"}
+
+const (
+	fractalNone = iota
+	fractalFBm
+	fractalRidged
+	fractalBillow
+	fractalTurbulence
+)
+
+// fractalNoise layers octaves of base noise, each lacunarity times the
+// frequency and gain times the amplitude of the previous one.
+type fractalNoise struct {
+	base noiseGenerator
+	mode int
+
+	octaves          int
+	lacunarity, gain float64
+
+	// tiled are the octaves of tileable noise, each repeating over the
+	// same period, and sampled at its own frequencies.
+	tiled []scaledNoise
+}
+
+// octaveOffset shifts each octave, so that they don't all line up at the
+// origin.
+func octaveOffset(i int) (float64, float64) {
+	return 17.31 * float64(i), -29.71 * float64(i)
+}
+
+// layer is octave i before its amplitude: base noise for fBm, folded for
+// billow and turbulence, and the sharp ridges of ridged multifractals,
+// without the weight of the octaves before.
+func (f fractalNoise) layer(i int, x, y float64) float64 {
+	freq := math.Pow(f.lacunarity, float64(i))
+	base, fx, fy := f.base, freq, freq
+	if f.tiled != nil {
+		base, fx, fy = f.tiled[i].base, f.tiled[i].fx, f.tiled[i].fy
+	}
+	ox, oy := octaveOffset(i)
+	n := base.Noise2D(x*fx+ox, y*fy+oy)
+
+	switch f.mode {
+	case fractalRidged:
+		r := 1 - math.Abs(n)
+		return r * r
+	case fractalBillow:
+		return 2*math.Abs(n) - 1
+	case fractalTurbulence:
+		return math.Abs(n)
+	}
+
+	return n
+}
+
+func (f fractalNoise) Noise2D(x, y float64) float64 {
+	if f.mode == fractalNone || f.octaves < 1 {
+		return f.base.Noise2D(x, y)
+	}
+
+	var sum, total float64
+	amp, weight := 1.0, 1.0
+	for i := range f.octaves {
+		v := f.layer(i, x, y)
+		if f.mode == fractalRidged {
+			// Musgrave's ridged multifractal: the ridges of an octave are
+			// only as sharp as those of the one before
+			v *= weight
+			weight = max(0, min(1, 2*v))
+		}
+		sum += amp * v
+		total += amp
+		amp *= f.gain
+	}
+	sum /= total
+
+	// ridged multifractals sum positive layers, in [0, 1], while
+	// turbulence is left there as Perlin defined it
+	if f.mode == fractalRidged {
+		return 2*sum - 1
+	}
+
+	return sum
+}
+
+// octavePreview draws layer i over the w×h image the Noise tab renders,
+// scaled down to size pixels wide.
+func (f fractalNoise) octavePreview(i, size, w, h int, xDivide, zDivide float64) image.Image {
+	ph := max(1, size*h/max(1, w))
+	img := image.NewGray(image.Rect(0, 0, size, ph))
+
+	for py := range ph {
+		for px := range size {
+			x := float64(px*w/size) / xDivide
+			y := float64(py*h/ph) / zDivide
+			v := f.layer(i, x, y)
+			if f.mode == fractalRidged || f.mode == fractalTurbulence {
+				v = 2*v - 1
+			}
+			img.SetGray(px, py, color.Gray{uint8(max(0, min(255, (v+1)/2*255)))})
+		}
+	}
+
+	return img
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
-	"crypto/rand"
 	"fmt"
 	"image/color"
+	"iter"
+	"maps"
 	"math"
-	r2 "math/rand"
+	"math/big"
+	"math/rand/v2"
+	"regexp"
+	"slices"
 	"strings"
-	"unsafe"
 
 	"github.com/Knetic/govaluate"
 	"github.com/aquilax/go-perlin"
@@ -17,7 +20,57 @@ type Graph func(x, y float64) (x1, y1 []float64)
 
 var precision = 0.01
 
-var graphs = make(map[color.Color][]Graph)
+// plot is a row on the equations page, identified by its key in graphs
+// rather than by its color so that rows can share or change colors.
+type plot struct {
+	color    color.Color
+	graphs   []Graph
+	points   *dataset
+	fit      *fit
+	domain   *domainColoring
+	field    *heatmap
+	vectors  *vectorField
+	sequence *recurrence
+	pieces   *piecewise
+	linear   *matrixPlot
+
+	// relation is an equation or inequality drawn with interval
+	// arithmetic.
+	relation *intervalPlot
+
+	// precise evaluates graphs with big.Float, when the view is too small
+	// for float64.
+	precise *preciseGraph
+
+	// axes are the names and units of the axes for equations with units.
+	axes *axisUnits
+}
+
+// clear removes everything p plots, keeping its color.
+func (p *plot) clear() {
+	*p = plot{color: p.color}
+}
+
+var graphs = make(map[int]*plot)
+
+var lastPlotID int
+
+func newPlotID() int {
+	lastPlotID++
+	return lastPlotID
+}
+
+// palette is the Okabe-Ito colorblind-safe palette, without black as the
+// graph is drawn over the (dark) window background.
+var palette = []color.Color{
+	color.RGBA{0xe6, 0x9f, 0x00, 0xff}, // orange
+	color.RGBA{0x56, 0xb4, 0xe9, 0xff}, // sky blue
+	color.RGBA{0x00, 0x9e, 0x73, 0xff}, // bluish green
+	color.RGBA{0xf0, 0xe4, 0x42, 0xff}, // yellow
+	color.RGBA{0x00, 0x72, 0xb2, 0xff}, // blue
+	color.RGBA{0xd5, 0x5e, 0x00, 0xff}, // vermillion
+	color.RGBA{0xcc, 0x79, 0xa7, 0xff}, // reddish purple
+}
 
 type pc1 struct {
 	a, b, x float64
@@ -25,7 +78,7 @@ type pc1 struct {
 	seed    int64
 }
 
-var perlinCache = map[pc1]float64{}
+var perlinCache = newMemo[pc1, float64](1 << 16)
 
 func parseMultiequationGraph(str string) (Graph, error) {
 	z, err := parseMultiequationExpressions(str)
@@ -36,22 +89,15 @@ func parseMultiequationGraph(str string) (Graph, error) {
 	return func(x, y float64) (x1, y1 []float64) {
 		x1, y1 = make([]float64, len(z[0])), make([]float64, len(z[1]))
 
-		params := map[string]interface{}{
-			"x":     x,
-			"y":     y,
-			"π":     math.Pi,
-			"e":     math.E,
-			"max64": math.MaxFloat64,
-			"min64": math.SmallestNonzeroFloat64,
-		}
+		params := graphParams{x, y}
 
 		for i, q := range z[0] {
-			v, _ := q.Evaluate(params)
+			v, _ := q.Eval(params)
 			x1[i], _ = v.(float64)
 		}
 
 		for i, q := range z[1] {
-			v, _ := q.Evaluate(params)
+			v, _ := q.Eval(params)
 			y1[i], _ = v.(float64)
 		}
 
@@ -59,12 +105,76 @@ func parseMultiequationGraph(str string) (Graph, error) {
 	}, nil
 }
 
+// sceneSeed seeds every random function, so that a scene renders the same
+// way each time until it is rerolled.
+var sceneSeed uint64 = 39530
+
+// rng is the stream behind rnd(), rndnormal and rndint, restarted from
+// sceneSeed on every render.
+var rng = rand.New(rand.NewPCG(sceneSeed, 0))
+
+func reseed() {
+	rng = rand.New(rand.NewPCG(sceneSeed, 0))
+}
+
+// graphParams resolves the variables of an equation at a sample point.
+type graphParams struct {
+	x, y float64
+}
+
+func (p graphParams) Get(name string) (interface{}, error) {
+	switch name {
+	case "x":
+		return p.x, nil
+	case "y":
+		return p.y, nil
+	case "π":
+		return math.Pi, nil
+	case "e":
+		return math.E, nil
+	case "max64":
+		return math.MaxFloat64, nil
+	case "min64":
+		return math.SmallestNonzeroFloat64, nil
+	}
+
+	if v, ok := datasetColumn(name); ok {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("no parameter '%s'", name)
+}
+
+// columnRef matches references to imported data columns such as data.y,
+// which govaluate only accepts as escaped [data.y] variables.
+var columnRef = regexp.MustCompile(`[A-Za-z_]\w*\.[A-Za-z_]\w*`)
+
 var functions = map[string]govaluate.ExpressionFunction{
 	"sqrt": newFloat64Func(math.Sqrt),
 	"abs":  newFloat64Func(math.Abs),
 	"rnd": func(arguments ...interface{}) (interface{}, error) {
-		return r2.Float64(), nil
+		switch len(arguments) {
+		case 0:
+			return rng.Float64(), nil
+		case 1:
+			s, _ := arguments[0].(float64)
+			return rand.New(rand.NewPCG(sceneSeed, math.Float64bits(s))).Float64(), nil
+		}
+
+		return nil, fmt.Errorf("expected no argument or a seed")
 	},
+	"rndnormal": new2Float64Func(func(mu, sigma float64) float64 {
+		return mu + sigma*rng.NormFloat64()
+	}),
+	"rndint": new2Float64Func(func(a, b float64) float64 {
+		// beyond 2^53 the integers in between aren't all floats anyway
+		a, b = math.Ceil(a), math.Floor(b)
+		if !(b-a >= 0 && b-a <= 1<<53) {
+			return math.NaN()
+		}
+
+		return a + float64(rng.Int64N(int64(b-a)+1))
+	}),
 	"acos":  newFloat64Func(math.Acos),
 	"acosh": newFloat64Func(math.Acosh),
 	"asin":  newFloat64Func(math.Asin),
@@ -74,6 +184,10 @@ var functions = map[string]govaluate.ExpressionFunction{
 	"cbrt":  newFloat64Func(math.Cbrt),
 	"ceil":  newFloat64Func(math.Ceil),
 	"cos":   newFloat64Func(math.Cos),
+	"exp":   newFloat64Func(math.Exp),
+	"log":   newFloat64Func(math.Log),
+	"log2":  newFloat64Func(math.Log2),
+	"log10": newFloat64Func(math.Log10),
 	"cosh":  newFloat64Func(math.Cosh),
 	"floor": newFloat64Func(math.Floor),
 	"sin":   newFloat64Func(math.Sin),
@@ -85,14 +199,14 @@ var functions = map[string]govaluate.ExpressionFunction{
 			return 0, fmt.Errorf("must have 5 arguments: alpha, beta, n, seed, x")
 		}
 		pc := pc1{arguments[0].(float64), arguments[1].(float64), arguments[4].(float64), int32(arguments[2].(float64)), int64(arguments[3].(float64))}
-		v, ok := perlinCache[pc]
+		v, ok := perlinCache.get(pc)
 		if ok {
 			return v, nil
 		}
 
 		p := perlin.NewPerlin(arguments[0].(float64), arguments[1].(float64), int32(arguments[2].(float64)), int64(arguments[3].(float64)))
 		v = p.Noise1D(arguments[4].(float64))
-		perlinCache[pc] = v
+		perlinCache.put(pc, v)
 
 		return v, nil
 	},
@@ -120,6 +234,75 @@ var functions = map[string]govaluate.ExpressionFunction{
 	"remainder": new2Float64Func(math.Remainder),
 	"copysign":  new2Float64Func(math.Copysign),
 	"hypot":     new2Float64Func(math.Hypot),
+	"mean":      newListFunc(mean),
+	"sum":       newListFunc(sum),
+	"count":     newListFunc(func(l []float64) float64 { return float64(len(l)) }),
+	"median":    newListFunc(func(l []float64) float64 { return quantile(l, 0.5) }),
+	"var":       newListFunc(variance),
+	"stdev":     newListFunc(func(l []float64) float64 { return math.Sqrt(variance(l)) }),
+	"quantile": func(arguments ...interface{}) (interface{}, error) {
+		l := listArgs(arguments)
+		if len(l) < 2 {
+			return nil, fmt.Errorf("must have arguments: p, list")
+		}
+
+		return quantile(l[1:], l[0]), nil
+	},
+	"gamma":     newFloat64Func(math.Gamma),
+	"lgamma":    newFloat64Func(lgamma),
+	"erf":       newFloat64Func(math.Erf),
+	"erfc":      newFloat64Func(math.Erfc),
+	"beta":      new2Float64Func(beta),
+	"factorial": newFloat64Func(factorial),
+	"nCr":       new2Float64Func(choose),
+	"nPr":       new2Float64Func(permutations),
+	"normalpdf": newLocationScaleFunc(normalPDF, true),
+	"normalcdf": newLocationScaleFunc(normalCDF, false),
+	"invnorm": func(arguments ...interface{}) (interface{}, error) {
+		a := listArgs(arguments)
+		switch len(a) {
+		case 1:
+			return math.Sqrt2 * math.Erfinv(2*a[0]-1), nil
+		case 3:
+			return a[1] + a[2]*math.Sqrt2*math.Erfinv(2*a[0]-1), nil
+		}
+
+		return nil, fmt.Errorf("must have 1 or 3 arguments: p, mu, sigma")
+	},
+	"binompdf":   newNFloat64Func(func(a []float64) float64 { return binomialPDF(a[0], a[1], a[2]) }, "n", "p", "k"),
+	"binomcdf":   newNFloat64Func(func(a []float64) float64 { return binomialCDF(a[0], a[1], a[2]) }, "n", "p", "k"),
+	"poissonpdf": newNFloat64Func(func(a []float64) float64 { return poissonPDF(a[0], a[1]) }, "lambda", "k"),
+	"poissoncdf": newNFloat64Func(func(a []float64) float64 { return poissonCDF(a[0], a[1]) }, "lambda", "k"),
+	"tpdf":       newNFloat64Func(func(a []float64) float64 { return tPDF(a[0], a[1]) }, "x", "df"),
+	"tcdf":       newNFloat64Func(func(a []float64) float64 { return tCDF(a[0], a[1]) }, "x", "df"),
+	"invt": newNFloat64Func(func(a []float64) float64 {
+		return invertCDF(func(x float64) float64 { return tCDF(x, a[1]) }, a[0], -10, 10)
+	}, "p", "df"),
+	"chisqpdf": newNFloat64Func(func(a []float64) float64 { return chiSquaredPDF(a[0], a[1]) }, "x", "k"),
+	"chisqcdf": newNFloat64Func(func(a []float64) float64 { return chiSquaredCDF(a[0], a[1]) }, "x", "k"),
+	"invchisq": newNFloat64Func(func(a []float64) float64 {
+		return invertCDF(func(x float64) float64 { return chiSquaredCDF(x, a[1]) }, a[0], 0, 2*a[1]+10)
+	}, "p", "k"),
+}
+
+// listArgs flattens function arguments made of numbers and data columns
+// into a single list, leaving out missing (NaN) data.
+func listArgs(arguments []interface{}) []float64 {
+	var l []float64
+	for _, a := range arguments {
+		switch a := a.(type) {
+		case float64:
+			l = append(l, a)
+		case []float64:
+			for _, v := range a {
+				if !math.IsNaN(v) {
+					l = append(l, v)
+				}
+			}
+		}
+	}
+
+	return l
 }
 
 func new2Float64Func(g func(float64, float64) float64) govaluate.ExpressionFunction {
@@ -155,13 +338,13 @@ func parseMultiequationExpressions(str string) ([2][]*govaluate.EvaluableExpress
 
 	var err error
 	for i, eq := range s[0] {
-		eqs[0][i], err = govaluate.NewEvaluableExpressionWithFunctions(eq, functions)
+		eqs[0][i], err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(eq), functions)
 		if err != nil {
 			return eqs, err
 		}
 	}
 	for i, eq := range s[1] {
-		eqs[1][i], err = govaluate.NewEvaluableExpressionWithFunctions(eq, functions)
+		eqs[1][i], err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(eq), functions)
 		if err != nil {
 			return eqs, err
 		}
@@ -170,6 +353,15 @@ func parseMultiequationExpressions(str string) ([2][]*govaluate.EvaluableExpress
 	return eqs, nil
 }
 
+// prepareExpression rewrites the notation Qraph accepts into govaluate's,
+// x^2 for powers, data.y for data columns and summation(expr, k, a, b)
+// for series.
+func prepareExpression(eq string) string {
+	eq = strings.ReplaceAll(eq, "^", "**")
+
+	return rewriteSeries(columnRef.ReplaceAllString(eq, "[$0]"))
+}
+
 func parseMultiequation(str string) [2][]string {
 	str = strings.TrimSpace(str)
 
@@ -285,18 +477,80 @@ s:
 
 func applyGraph(f Graph, c color.Color) {
 	if c == nil {
-		c = colorrand()
+		c = nextColor()
 	}
-	maxX, maxY := float64(graph.Rect.Max.X), float64(graph.Rect.Max.Y)
-
-	dx, dy := maxX/2, maxY/2
-
-	for x, y := -maxX, -maxY; x < maxX && y < maxY; x, y = x+precision, y+precision {
+	for x, y := range samples() {
 		xs, ys := f(x, y)
 
 		for _, x1 := range xs {
 			for _, y1 := range ys {
-				setpix(dx+x1, dy-y1, c)
+				px, py := toPixel(x1, y1)
+				setpix(px, py, c)
+			}
+		}
+	}
+}
+
+// scale is the number of pixels per graph unit.
+var scale = 1.0
+
+// centerX, centerY is the point shown in the center of the image. center
+// keeps it at full precision, for zooms deeper than float64 can tell
+// apart.
+var (
+	centerX, centerY float64
+	center           = [2]*big.Float{new(big.Float).SetPrec(centerPrecision), new(big.Float).SetPrec(centerPrecision)}
+)
+
+const centerPrecision = 2048
+
+// moveCenter moves the center of the view by dx, dy graph units.
+func moveCenter(dx, dy float64) {
+	center[0].Add(center[0], big.NewFloat(dx))
+	center[1].Add(center[1], big.NewFloat(dy))
+	centerX, _ = center[0].Float64()
+	centerY, _ = center[1].Float64()
+}
+
+// zoom multiplies the scale by f, keeping the point at the image
+// coordinates px, py in place.
+func zoom(f, px, py float64) {
+	dx := (px - float64(graph.Rect.Dx())/2) / scale
+	dy := (float64(graph.Rect.Dy())/2 - py) / scale
+	moveCenter(dx*(1-1/f), dy*(1-1/f))
+	scale *= f
+}
+
+// toPixel maps graph coordinates to image coordinates.
+func toPixel(x, y float64) (px, py float64) {
+	return float64(graph.Rect.Dx())/2 + (x-centerX)*scale, float64(graph.Rect.Dy())/2 - (y-centerY)*scale
+}
+
+// fromPixel maps image coordinates back to graph coordinates.
+func fromPixel(px, py float64) (x, y float64) {
+	return centerX + (px-float64(graph.Rect.Dx())/2)/scale, centerY + (float64(graph.Rect.Dy())/2-py)/scale
+}
+
+// maxSamples bounds how many points samples yields, which would otherwise
+// grow without bound as the view zooms out past the default one.
+const maxSamples = 1 << 18
+
+// samples yields the points where graphs are sampled, x and y moving
+// together across twice the view, at most half a pixel apart.
+func samples() iter.Seq2[float64, float64] {
+	maxX, maxY := float64(graph.Rect.Max.X)/scale, float64(graph.Rect.Max.Y)/scale
+	step := math.Min(precision, 0.5/scale)
+	if scale < 1 {
+		// zoomed out, the points spread apart with the view, as many as
+		// the default view has at precision, or maxSamples if fewer
+		span := 2 * math.Min(maxX, maxY) * scale
+		step = math.Max(step, math.Min(precision, span/maxSamples)/scale)
+	}
+
+	return func(yield func(x, y float64) bool) {
+		for k := 0.0; k*step < 2*maxX && k*step < 2*maxY; k++ {
+			if !yield(centerX-maxX+k*step, centerY-maxY+k*step) {
+				return
 			}
 		}
 	}
@@ -312,42 +566,125 @@ func setpix(x, y float64, c color.Color) {
 	graph.Set(int(x), int(y+2), c)
 }
 
-func colorrand() color.Color {
-	var color = color.RGBA{A: 255}
-	for {
-		rand.Read(unsafe.Slice((*byte)(unsafe.Pointer(&color)), 3))
-		if _, ok := graphs[color]; !ok {
-			break
+// nextColor returns the first palette color not used by any plot, cycling
+// through the palette once every color is taken.
+func nextColor() color.Color {
+	used := make(map[color.Color]bool)
+	for _, p := range graphs {
+		used[p.color] = true
+	}
+
+	for _, c := range palette {
+		if !used[c] {
+			return c
 		}
 	}
 
-	return color
+	return palette[len(graphs)%len(palette)]
 }
 
-func addGraph(f Graph, c color.Color) {
+func addGraph(f Graph, c color.Color) int {
+	id := newPlotID()
+	graphs[id] = &plot{color: c, graphs: []Graph{f}}
 	applyGraph(f, c)
-	graphs[c] = append(graphs[c], f)
+
+	return id
+}
+
+// plotIDs returns the ids of all plots in the order they were added, so
+// that overlapping plots are always drawn the same way.
+func plotIDs() []int {
+	return slices.Sorted(maps.Keys(graphs))
 }
 
 func reset() {
 	clear(graph.Pix)
-	maxX, maxY := float64(graph.Rect.Max.X), float64(graph.Rect.Max.Y)
 
-	dx, dy := maxX/2, maxY/2
+	ids := plotIDs()
 
-	for x, y := -maxX, -maxY; x < maxX && y < maxY; x, y = x+precision, y+precision {
-		for c, graphs := range graphs {
-			for _, g := range graphs {
+	for _, id := range ids {
+		if f := graphs[id].fit; f != nil && f.version != f.data.version {
+			f.run()
+		}
+	}
+
+	reseed()
+
+	for _, id := range ids {
+		if d := graphs[id].domain; d != nil {
+			d.draw()
+		}
+		if h := graphs[id].field; h != nil {
+			h.draw()
+		}
+	}
+
+	for _, id := range ids {
+		if v := graphs[id].vectors; v != nil {
+			v.draw(graphs[id].color)
+		}
+		if m := graphs[id].linear; m != nil {
+			m.draw(graphs[id].color)
+		}
+		if r := graphs[id].relation; r != nil {
+			r.draw(graphs[id].color)
+		}
+	}
+
+	deep := preciseView()
+	for x, y := range samples() {
+		for _, id := range ids {
+			p := graphs[id]
+			if deep && p.precise != nil {
+				continue
+			}
+			for _, g := range p.graphs {
 				xs, ys := g(x, y)
 
 				for _, x1 := range xs {
 					for _, y1 := range ys {
-						setpix(dx+x1, dy-y1, c)
+						px, py := toPixel(x1, y1)
+						setpix(px, py, p.color)
 					}
 				}
 			}
 		}
 	}
+
+	if deep {
+		for _, id := range ids {
+			if p := graphs[id]; p.precise != nil {
+				p.precise.draw(p.color)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if p := graphs[id]; p.points != nil {
+			p.points.draw(p.color)
+		}
+		if p := graphs[id]; p.sequence != nil {
+			p.sequence.draw(p.color)
+		}
+		if p := graphs[id]; p.pieces != nil {
+			p.pieces.draw(p.color)
+		}
+	}
+
+	for _, id := range ids {
+		if a := graphs[id].axes; a != nil {
+			drawAxisLabels(a)
+			break
+		}
+	}
+
+	right := graph.Rect.Max.X - 10
+	for _, id := range ids {
+		if h := graphs[id].field; h != nil {
+			h.drawLegend(right)
+			right -= 100
+		}
+	}
 }
 
 func oneXandOneY(x, y float64) ([]float64, []float64) {
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Knetic/govaluate"
+)
+
+// TestRndint checks that rndint stays within its bounds, and is undefined
+// rather than panicking over bounds it can't draw between.
+func TestRndint(t *testing.T) {
+	tests := []struct {
+		a, b float64
+		nan  bool
+	}{
+		{1, 6, false},
+		{-2.5, 2.5, false},
+		{3, 3, false},
+		{-1 << 53, 0, false},
+		{6, 1, true},
+		{0.2, 0.8, true},
+		{math.NaN(), 1, true},
+		{1, math.NaN(), true},
+		{math.Inf(-1), 1, true},
+		{1, math.Inf(1), true},
+		{math.Inf(1), math.Inf(1), true},
+		{-1e19, 1e19, true},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			v, err := functions["rndint"](tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("rndint(%v, %v): %v", tt.a, tt.b, err)
+			}
+
+			got := v.(float64)
+			if tt.nan {
+				if !math.IsNaN(got) {
+					t.Fatalf("rndint(%v, %v) = %v, want NaN", tt.a, tt.b, got)
+				}
+				continue
+			}
+			if got != math.Trunc(got) || got < tt.a || got > tt.b {
+				t.Fatalf("rndint(%v, %v) = %v", tt.a, tt.b, got)
+			}
+		}
+	}
+}
+
+// TestSeries checks that summation and product fold their terms, and that
+// sum stays the sum of a list whatever its arguments.
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		eq   string
+		want float64
+	}{
+		{"sum(x, y, 1, 2)", 10},
+		{"sum(x, y)", 7},
+		{"summation(k^2, k, 1, 3)", 14},
+		{"summation(x*k, k, 1, y)", 30},
+		{"product(k, k, 1, 5)", 120},
+		{"product(x, k, 1, 0)", 1},
+		{"summation(product(j, j, 1, k), k, 1, 4)", 33},
+	}
+
+	for _, tt := range tests {
+		e, err := govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(tt.eq), functions)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.eq, err)
+		}
+		if got := evalFloat(e, graphParams{3, 4}); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.eq, got, tt.want)
+		}
+	}
+}
+
+// TestSamples checks that samples keeps to precision however fine, and
+// that zooming out doesn't multiply the points beyond the default view's.
+func TestSamples(t *testing.T) {
+	defer func(s, p float64) { scale, precision = s, p }(scale, precision)
+
+	tests := []struct {
+		scale, precision float64
+		step             float64
+		most             int
+	}{
+		{1, 0.01, 0.01, 0},
+		{1, 0.001, 0.001, 0},
+		{100, 0.001, 0.001, 0},
+		{1e4, 0.01, 0.5e-4, 0},
+		{1e-3, 0.01, 0, maxSamples},
+		{1e-6, 0.001, 0, 2400000},
+	}
+
+	for _, tt := range tests {
+		scale, precision = tt.scale, tt.precision
+		var xs []float64
+		for x := range samples() {
+			xs = append(xs, x)
+		}
+
+		if tt.step != 0 && math.Abs(xs[1]-xs[0]-tt.step) > tt.step*1e-6 {
+			t.Errorf("scale %v, precision %v: step %v, want %v", tt.scale, tt.precision, xs[1]-xs[0], tt.step)
+		}
+		if tt.most != 0 && len(xs) > tt.most+1 {
+			t.Errorf("scale %v, precision %v: %d samples, want at most %d", tt.scale, tt.precision, len(xs), tt.most)
+		}
+	}
+}
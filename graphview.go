@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// graphView shows the graph image, reporting taps in graph coordinates.
+// Dragging pans the view and scrolling zooms it around the cursor.
+type graphView struct {
+	widget.BaseWidget
+
+	img      *canvas.Image
+	onTapped func(x, y float64)
+
+	// onViewChanged is called once the center or the scale changed.
+	onViewChanged func()
+}
+
+func newGraphView(onTapped func(x, y float64), onViewChanged func()) *graphView {
+	v := &graphView{onTapped: onTapped, onViewChanged: onViewChanged}
+	v.img = canvas.NewImageFromImage(graph)
+	v.img.ScaleMode = canvas.ImageScalePixels
+	v.ExtendBaseWidget(v)
+
+	return v
+}
+
+func (v *graphView) Refresh() {
+	v.img.Refresh()
+}
+
+// Tapped maps the tap to the graph, the image being stretched over the
+// whole widget.
+func (v *graphView) Tapped(e *fyne.PointEvent) {
+	size := v.Size()
+	if v.onTapped == nil || size.Width == 0 || size.Height == 0 {
+		return
+	}
+
+	v.onTapped(fromPixel(v.toImage(e.Position)))
+}
+
+// toImage maps a position in the widget to image coordinates.
+func (v *graphView) toImage(pos fyne.Position) (px, py float64) {
+	size := v.Size()
+	return float64(pos.X/size.Width) * float64(graph.Rect.Dx()), float64(pos.Y/size.Height) * float64(graph.Rect.Dy())
+}
+
+func (v *graphView) Dragged(e *fyne.DragEvent) {
+	size := v.Size()
+	if size.Width == 0 || size.Height == 0 {
+		return
+	}
+
+	dx := float64(e.Dragged.DX/size.Width) * float64(graph.Rect.Dx()) / scale
+	dy := float64(e.Dragged.DY/size.Height) * float64(graph.Rect.Dy()) / scale
+	moveCenter(-dx, dy)
+	v.viewChanged()
+}
+
+func (v *graphView) DragEnd() {}
+
+// Scrolled zooms by 1% per step, keeping the point under the cursor in
+// place.
+func (v *graphView) Scrolled(e *fyne.ScrollEvent) {
+	size := v.Size()
+	if size.Width == 0 || size.Height == 0 || e.Scrolled.DY == 0 {
+		return
+	}
+
+	px, py := v.toImage(e.Position)
+	zoom(math.Pow(1.01, float64(e.Scrolled.DY)), px, py)
+	v.viewChanged()
+}
+
+func (v *graphView) viewChanged() {
+	if v.onViewChanged != nil {
+		v.onViewChanged()
+	}
+}
+
+func (v *graphView) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(v.img)
+}
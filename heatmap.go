@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// heatmap plots a scalar field z = f(x, y) as colors, with iso-contours
+// and a color bar.
+type heatmap struct {
+	f        *govaluate.EvaluableExpression
+	colormap colormap
+
+	// levels are the contour levels. When nil, contourCount levels are
+	// spread evenly over the values on screen.
+	levels       []float64
+	contourCount int
+	labels       bool
+
+	// min and max are the range of values on screen, as of the last draw.
+	min, max float64
+}
+
+func parseHeatmap(str string) (*heatmap, error) {
+	lhs, rhs, ok := strings.Cut(str, "=")
+	if !ok || strings.TrimSpace(lhs) != "z" {
+		return nil, fmt.Errorf("expected z = f(x, y)")
+	}
+
+	f, err := govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(rhs), functions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &heatmap{f: f, colormap: colormaps["Viridis"], contourCount: 8, labels: true}, nil
+}
+
+// parseLevels parses contour levels given either as a count, such as 10,
+// or as a list, such as 0, 0.5, 1.
+func parseLevels(s string) (levels []float64, count int, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) == 1 {
+		count, err = strconv.Atoi(strings.TrimSpace(s))
+		if err == nil && count >= 0 {
+			return nil, count, nil
+		}
+	}
+
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		levels = append(levels, v)
+	}
+
+	return levels, 0, nil
+}
+
+func (h *heatmap) contourLevels() []float64 {
+	if h.levels != nil {
+		return h.levels
+	}
+
+	levels := make([]float64, h.contourCount)
+	for k := range levels {
+		levels[k] = h.min + (h.max-h.min)*float64(k+1)/float64(h.contourCount+1)
+	}
+
+	return levels
+}
+
+func (h *heatmap) draw() {
+	b := graph.Rect
+	w, ht := b.Dx(), b.Dy()
+
+	values := make([]float64, w*ht)
+	h.min, h.max = math.Inf(1), math.Inf(-1)
+	for py := 0; py < ht; py++ {
+		for px := 0; px < w; px++ {
+			x, y := fromPixel(float64(px), float64(py))
+			v := evalFloat(h.f, graphParams{x, y})
+			values[py*w+px] = v
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				h.min, h.max = math.Min(h.min, v), math.Max(h.max, v)
+			}
+		}
+	}
+	if h.max <= h.min {
+		h.max = h.min + 1
+	}
+
+	for py := 0; py < ht; py++ {
+		for px := 0; px < w; px++ {
+			if v := values[py*w+px]; !math.IsNaN(v) {
+				graph.Set(b.Min.X+px, b.Min.Y+py, h.colormap.at((v-h.min)/(h.max-h.min)))
+			}
+		}
+	}
+
+	// labels are placed as far as possible from each other
+	var placed []image.Point
+
+	for _, level := range h.contourLevels() {
+		lineColor := color.Color(color.Black)
+		if c := h.colormap.at((level - h.min) / (h.max - h.min)); int(c.R)+int(c.G)+int(c.B) < 3*0x80 {
+			lineColor = color.White
+		}
+
+		var crossings []image.Point
+		for py := 0; py < ht-1; py++ {
+			for px := 0; px < w-1; px++ {
+				v := values[py*w+px] - level
+				right := values[py*w+px+1] - level
+				below := values[(py+1)*w+px] - level
+				if v*right < 0 || v*below < 0 || (v == 0 && right != 0) {
+					graph.Set(b.Min.X+px, b.Min.Y+py, lineColor)
+					crossings = append(crossings, image.Pt(b.Min.X+px, b.Min.Y+py))
+				}
+			}
+		}
+
+		if h.labels && len(crossings) != 0 {
+			best, bestDistance := crossings[len(crossings)/2], -1
+			for i := 0; i < len(crossings); i += 1 + len(crossings)/500 {
+				c := crossings[i]
+				edge := min(c.X-b.Min.X, b.Max.X-c.X, c.Y-b.Min.Y, b.Max.Y-c.Y)
+				d := edge * edge
+				for _, p := range placed {
+					d = min(d, (c.X-p.X)*(c.X-p.X)+(c.Y-p.Y)*(c.Y-p.Y))
+				}
+				if d > bestDistance {
+					best, bestDistance = c, d
+				}
+			}
+
+			placed = append(placed, best)
+			drawLabel(graph, best.X+3, best.Y-3, strconv.FormatFloat(level, 'g', 3, 64))
+		}
+	}
+}
+
+// drawLegend draws the color bar of the heatmap with its right edge at
+// right.
+func (h *heatmap) drawLegend(right int) {
+	const barWidth, barHeight, top = 16, 200, 10
+
+	minLabel := strconv.FormatFloat(h.min, 'g', 4, 64)
+	maxLabel := strconv.FormatFloat(h.max, 'g', 4, 64)
+	textWidth := 7 * max(len(minLabel), len(maxLabel))
+
+	box := image.Rect(right-barWidth-textWidth-12, top, right, top+barHeight+14)
+	draw.Draw(graph, box, image.NewUniform(color.RGBA{0, 0, 0, 0xa0}), image.Point{}, draw.Over)
+
+	barX := box.Min.X + 4
+	for y := 0; y < barHeight; y++ {
+		c := h.colormap.at(1 - float64(y)/float64(barHeight-1))
+		for x := 0; x < barWidth; x++ {
+			graph.Set(barX+x, top+7+y, c)
+		}
+	}
+
+	drawText(graph, barX+barWidth+4, top+7+11, maxLabel, color.White)
+	drawText(graph, barX+barWidth+4, top+7+barHeight, minLabel, color.White)
+
+	for _, level := range h.contourLevels() {
+		y := top + 7 + int((1-(level-h.min)/(h.max-h.min))*float64(barHeight-1))
+		if y >= top+7 && y < top+7+barHeight {
+			for x := -3; x < barWidth+3; x++ {
+				graph.Set(barX+x, y, color.White)
+			}
+		}
+	}
+}
+
+// drawText draws s with its baseline starting at x, y.
+func drawText(img draw.Image, x, y int, s string, c color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// drawLabel draws s in white over a dark box, to be legible over any plot.
+func drawLabel(img draw.Image, x, y int, s string) {
+	box := image.Rect(x-2, y-11, x+7*len([]rune(s))+2, y+3)
+	draw.Draw(img, box, image.NewUniform(color.RGBA{0, 0, 0, 0xa0}), image.Point{}, draw.Over)
+	drawText(img, x, y, s, color.White)
+}
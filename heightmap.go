@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// heightField is a render of the Noise tab normalized to heights in
+// [0, 1], width×height row by row, for terrain tools.
+type heightField struct {
+	h             []float64
+	width, height int
+}
+
+func newHeightField(values []float64, width, height int, lo, hi float64) heightField {
+	h := make([]float64, len(values))
+	for i, v := range values {
+		h[i] = normalizeValue(v, lo, hi)
+	}
+
+	return heightField{h, width, height}
+}
+
+func (f heightField) at(x, y int) float64 {
+	return f.h[y*f.width+x]
+}
+
+// gray16 is the 16-bit grayscale heightmap.
+func (f heightField) gray16() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, f.width, f.height))
+	for y := range f.height {
+		for x := range f.width {
+			img.SetGray16(x, y, color.Gray16{uint16(f.at(x, y)*0xffff + 0.5)})
+		}
+	}
+
+	return img
+}
+
+// writeR32 writes the heights as raw little-endian float32, row by row, as
+// .r32 and .raw heightmaps are.
+func (f heightField) writeR32(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	buf := make([]byte, 4)
+	for _, v := range f.h {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(v)))
+		if _, err := b.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return b.Flush()
+}
+
+// gridSteps are the rows or columns of a mesh decimated to every step-th
+// sample, keeping the last one so that the mesh covers the whole field.
+// An empty field has none.
+func gridSteps(n, step int) []int {
+	if n < 1 {
+		return nil
+	}
+
+	var s []int
+	for i := 0; i < n; i += step {
+		s = append(s, i)
+	}
+	if s[len(s)-1] != n-1 {
+		s = append(s, n-1)
+	}
+
+	return s
+}
+
+// triangles calls emit with the corners of each triangle of the mesh of the
+// field, one unit a sample apart and scale units high, y up and
+// counterclockwise seen from above.
+func (f heightField) triangles(scale float64, step int, emit func(a, b, c [3]float64) error) error {
+	xs, ys := gridSteps(f.width, max(1, step)), gridSteps(f.height, max(1, step))
+	vertex := func(i, j int) [3]float64 {
+		return [3]float64{float64(xs[i]), f.at(xs[i], ys[j]) * scale, float64(ys[j])}
+	}
+
+	for j := range len(ys) - 1 {
+		for i := range len(xs) - 1 {
+			a, b, c, d := vertex(i, j), vertex(i, j+1), vertex(i+1, j), vertex(i+1, j+1)
+			if err := emit(a, b, c); err != nil {
+				return err
+			}
+			if err := emit(c, b, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeOBJ writes the mesh of the field as a Wavefront OBJ, sharing the
+// vertices between triangles.
+func (f heightField) writeOBJ(w io.Writer, scale float64, step int) error {
+	b := bufio.NewWriter(w)
+	xs, ys := gridSteps(f.width, max(1, step)), gridSteps(f.height, max(1, step))
+
+	fmt.Fprintf(b, "# %d×%d heightmap, %d×%d vertices\n", f.width, f.height, len(xs), len(ys))
+	for _, y := range ys {
+		for _, x := range xs {
+			fmt.Fprintf(b, "v %d %g %d\n", x, f.at(x, y)*scale, y)
+		}
+	}
+
+	// vertices are numbered from 1, row by row
+	for j := range len(ys) - 1 {
+		for i := range len(xs) - 1 {
+			a := j*len(xs) + i + 1
+			c := a + 1
+			bb := a + len(xs)
+			d := bb + 1
+			fmt.Fprintf(b, "f %d %d %d\nf %d %d %d\n", a, bb, c, c, bb, d)
+		}
+	}
+
+	return b.Flush()
+}
+
+// writeSTL writes the mesh of the field as a binary STL, rotated to be z
+// up as STL files usually are.
+func (f heightField) writeSTL(w io.Writer, scale float64, step int) error {
+	b := bufio.NewWriter(w)
+	xs, ys := gridSteps(f.width, max(1, step)), gridSteps(f.height, max(1, step))
+
+	header := make([]byte, 80)
+	copy(header, "heightmap")
+	b.Write(header)
+	binary.Write(b, binary.LittleEndian, uint32(2*(len(xs)-1)*(len(ys)-1)))
+
+	zUp := func(v [3]float64) [3]float32 {
+		return [3]float32{float32(v[0]), float32(-v[2]), float32(v[1])}
+	}
+	err := f.triangles(scale, step, func(p, q, r [3]float64) error {
+		u := [3]float64{q[0] - p[0], q[1] - p[1], q[2] - p[2]}
+		v := [3]float64{r[0] - p[0], r[1] - p[1], r[2] - p[2]}
+		n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+		if l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2]); l > 0 {
+			n = [3]float64{n[0] / l, n[1] / l, n[2] / l}
+		}
+
+		for _, c := range [][3]float32{zUp(n), zUp(p), zUp(q), zUp(r)} {
+			if err := binary.Write(b, binary.LittleEndian, c); err != nil {
+				return err
+			}
+		}
+		return binary.Write(b, binary.LittleEndian, uint16(0))
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.Flush()
+}
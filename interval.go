@@ -0,0 +1,1361 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// intervalMode draws equations and inequalities in x and y with interval
+// arithmetic over the pixels, as GrafEq does: a pixel is drawn when the
+// curve provably passes through it, left out when it provably doesn't,
+// and drawn faded when neither could be proven, so that no thin feature
+// is ever missed.
+var intervalMode = false
+
+// interval encloses the values of an expression over a box of x and y.
+type interval struct {
+	lo, hi float64
+
+	// def is whether the expression is defined over the whole box, and
+	// cont whether it is continuous over it.
+	def, cont bool
+}
+
+func pointInterval(v float64) interval {
+	if math.IsNaN(v) {
+		return emptyInterval()
+	}
+
+	return interval{v, v, true, true}
+}
+
+// emptyInterval is an expression defined nowhere in the box.
+func emptyInterval() interval {
+	return interval{math.Inf(1), math.Inf(-1), false, false}
+}
+
+// entireInterval is all that is known of an expression that isn't
+// enclosed better.
+func entireInterval() interval {
+	return interval{math.Inf(-1), math.Inf(1), false, false}
+}
+
+func (a interval) empty() bool {
+	return !(a.lo <= a.hi)
+}
+
+func (a interval) point() bool {
+	return a.lo == a.hi
+}
+
+// bounds returns the interval between lo and hi, rounded outwards by an
+// ulp to cover the rounding of the operation that computed them. NaN
+// bounds stand for unbounded ones.
+func bounds(lo, hi float64, def, cont bool) interval {
+	if math.IsNaN(lo) {
+		lo = math.Inf(-1)
+	}
+	if math.IsNaN(hi) {
+		hi = math.Inf(1)
+	}
+
+	return interval{math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1)), def, cont}
+}
+
+// hull is the smallest interval containing a and b.
+func hull(a, b interval) interval {
+	if a.empty() {
+		return interval{b.lo, b.hi, false, false}
+	}
+	if b.empty() {
+		return interval{a.lo, a.hi, false, false}
+	}
+
+	return interval{min(a.lo, b.lo), max(a.hi, b.hi), a.def && b.def, false}
+}
+
+// restrict intersects a with the domain [lo, hi] of a function, which is
+// then not defined over the whole box if a goes beyond it.
+func (a interval) restrict(lo, hi float64) interval {
+	if a.empty() || a.hi < lo || a.lo > hi {
+		return emptyInterval()
+	}
+	if a.lo < lo || a.hi > hi {
+		a.def, a.cont = false, false
+		a.lo, a.hi = max(a.lo, lo), min(a.hi, hi)
+	}
+
+	return a
+}
+
+// increasing applies the increasing function f to a.
+func (a interval) increasing(f func(float64) float64) interval {
+	if a.empty() {
+		return a
+	}
+	if a.point() {
+		return pointFunc(f(a.lo), a)
+	}
+
+	return bounds(f(a.lo), f(a.hi), a.def, a.cont)
+}
+
+func (a interval) decreasing(f func(float64) float64) interval {
+	if a.empty() {
+		return a
+	}
+	if a.point() {
+		return pointFunc(f(a.lo), a)
+	}
+
+	return bounds(f(a.hi), f(a.lo), a.def, a.cont)
+}
+
+// pointFunc is the value v of a function at the point a, rounded outwards.
+func pointFunc(v float64, a interval) interval {
+	if math.IsNaN(v) {
+		return emptyInterval()
+	}
+
+	return bounds(v, v, a.def, a.cont)
+}
+
+// truthInterval is the interval of a comparison: [1, 1] when it holds
+// over the whole box, [0, 0] when it holds nowhere and [0, 1] otherwise.
+func truthInterval(holds, fails bool, a, b interval) interval {
+	if a.empty() || b.empty() {
+		return emptyInterval()
+	}
+
+	def := a.def && b.def
+	switch {
+	case holds:
+		return interval{1, 1, def, true}
+	case fails:
+		return interval{0, 0, def, true}
+	}
+
+	return interval{0, 1, def, false}
+}
+
+// truth reports whether a is true, false or either over the box.
+func (a interval) truth() (holds, fails bool) {
+	return a.lo > 0 || a.hi < 0, a.lo == 0 && a.hi == 0
+}
+
+func (a interval) neg() interval {
+	return interval{-a.hi, -a.lo, a.def, a.cont}
+}
+
+func (a interval) add(b interval) interval {
+	if a.empty() || b.empty() {
+		return emptyInterval()
+	}
+
+	return bounds(a.lo+b.lo, a.hi+b.hi, a.def && b.def, a.cont && b.cont)
+}
+
+func (a interval) sub(b interval) interval {
+	return a.add(b.neg())
+}
+
+// mulBound multiplies bounds, 0 times an infinite bound being 0 since the
+// values it bounds are finite.
+func mulBound(a, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return a * b
+}
+
+func (a interval) mul(b interval) interval {
+	if a.empty() || b.empty() {
+		return emptyInterval()
+	}
+
+	p := []float64{mulBound(a.lo, b.lo), mulBound(a.lo, b.hi), mulBound(a.hi, b.lo), mulBound(a.hi, b.hi)}
+
+	return bounds(slices.Min(p), slices.Max(p), a.def && b.def, a.cont && b.cont)
+}
+
+// recip is 1/a, unbounded and neither defined nor continuous over the box
+// if a contains 0.
+func (a interval) recip() interval {
+	switch {
+	case a.empty() || a.lo == 0 && a.hi == 0:
+		return emptyInterval()
+	case a.lo > 0 || a.hi < 0:
+		return bounds(1/a.hi, 1/a.lo, a.def, a.cont)
+	case a.lo == 0:
+		return interval{math.Nextafter(1/a.hi, 0), math.Inf(1), false, false}
+	case a.hi == 0:
+		return interval{math.Inf(-1), math.Nextafter(1/a.lo, 0), false, false}
+	}
+
+	return entireInterval()
+}
+
+func (a interval) div(b interval) interval {
+	return a.mul(b.recip())
+}
+
+func (a interval) abs() interval {
+	switch {
+	case a.empty() || a.lo >= 0:
+		return a
+	case a.hi <= 0:
+		return a.neg()
+	}
+
+	return interval{0, max(-a.lo, a.hi), a.def, a.cont}
+}
+
+// pow is a^b: integer powers of any base, and other powers of
+// non-negative bases as math.Pow.
+func (a interval) pow(b interval) interval {
+	if a.empty() || b.empty() {
+		return emptyInterval()
+	}
+
+	if b.point() && b.lo == math.Trunc(b.lo) && math.Abs(b.lo) < 1<<53 {
+		n := b.lo
+		f := func(x float64) float64 { return math.Pow(x, n) }
+		switch {
+		case n == 0:
+			return interval{1, 1, a.def && b.def, a.cont && b.cont}
+		case n < 0:
+			return a.pow(pointInterval(-n)).recip()
+		case math.Mod(n, 2) == 1:
+			return a.increasing(f)
+		}
+
+		// even powers decrease then increase
+		m := a.abs()
+		return m.increasing(f)
+	}
+
+	r := emptyInterval()
+	if p := a.restrict(0, math.Inf(1)); !p.empty() {
+		v := []float64{math.Pow(p.lo, b.lo), math.Pow(p.lo, b.hi), math.Pow(p.hi, b.lo), math.Pow(p.hi, b.hi)}
+		r = bounds(slices.Min(v), slices.Max(v), p.def && b.def, p.cont && b.cont)
+		if p.lo == 0 && b.lo <= 0 {
+			// 0^b is 1 at b = 0 and infinite below
+			r.lo, r.hi = min(r.lo, 0), math.Inf(1)
+			r.cont = false
+		}
+	}
+
+	if a.lo < 0 {
+		// negative bases have integer powers only
+		neg := interval{a.lo, min(a.hi, 0), a.def, a.cont}
+		lo, hi := math.Ceil(b.lo), math.Floor(b.hi)
+		if hi-lo >= maxIntegers {
+			return interval{math.Inf(-1), math.Inf(1), false, false}
+		}
+		for n := lo; n <= hi; n++ {
+			r = hull(r, neg.pow(pointInterval(n)))
+		}
+		r.def, r.cont = false, false
+	}
+
+	return r
+}
+
+// mod is math.Mod(a, b) = a - b·trunc(a/b), or math.Remainder if nearest,
+// which rounds a/b to the nearest integer instead.
+func (a interval) mod(b interval, nearest bool) interval {
+	if a.empty() || b.empty() {
+		return emptyInterval()
+	}
+	if b.lo <= 0 && b.hi >= 0 {
+		return entireInterval()
+	}
+
+	round := math.Trunc
+	if nearest {
+		round = math.RoundToEven
+	}
+
+	if b.point() {
+		q := a.div(b)
+		if k := round(q.lo); k == round(q.hi) {
+			return a.sub(b.mul(pointInterval(k)))
+		}
+	}
+
+	m := max(math.Abs(b.lo), math.Abs(b.hi))
+	def := a.def && b.def
+	switch {
+	case nearest:
+		return interval{-m / 2, m / 2, def, false}
+	case a.lo >= 0:
+		return interval{0, m, def, false}
+	case a.hi <= 0:
+		return interval{-m, 0, def, false}
+	}
+
+	return interval{-m, m, def, false}
+}
+
+// periodic encloses a function of period 2π, such as cos, whose maximum 1
+// is at top + 2kπ and minimum -1 at top + π + 2kπ.
+func (a interval) periodic(f func(float64) float64, top float64) interval {
+	if a.empty() {
+		return a
+	}
+	if a.point() {
+		return pointFunc(f(a.lo), a)
+	}
+	if a.hi-a.lo >= 2*math.Pi || math.IsInf(a.lo, 0) || math.IsInf(a.hi, 0) {
+		return interval{-1, 1, a.def, a.cont}
+	}
+
+	// whether a contains one of c + 2kπ, erring on the side of yes
+	contains := func(c float64) bool {
+		const eps = 1e-9
+		return math.Ceil((a.lo-c)/(2*math.Pi)-eps) <= math.Floor((a.hi-c)/(2*math.Pi)+eps)
+	}
+
+	u, v := f(a.lo), f(a.hi)
+	r := bounds(min(u, v), max(u, v), a.def, a.cont)
+	if contains(top) {
+		r.hi = 1
+	}
+	if contains(top + math.Pi) {
+		r.lo = -1
+	}
+	r.lo, r.hi = max(r.lo, -1), min(r.hi, 1)
+
+	return r
+}
+
+// tan is increasing between its poles at π/2 + kπ.
+func (a interval) tan() interval {
+	if a.empty() || a.point() {
+		return a.increasing(math.Tan)
+	}
+
+	const eps = 1e-9
+	if a.hi-a.lo >= math.Pi || math.Ceil((a.lo-math.Pi/2)/math.Pi-eps) <= math.Floor((a.hi-math.Pi/2)/math.Pi+eps) {
+		return entireInterval()
+	}
+
+	return a.increasing(math.Tan)
+}
+
+// steps encloses floor or ceil, which are continuous over a only if it
+// doesn't cross a step.
+func (a interval) steps(f func(float64) float64) interval {
+	lo, hi := f(a.lo), f(a.hi)
+
+	return interval{lo, hi, a.def, a.cont && lo == hi}
+}
+
+// valley encloses a function decreasing down to its minimum at x0 and
+// increasing after, such as cosh at 0.
+func (a interval) valley(f func(float64) float64, x0 float64) interval {
+	switch {
+	case a.empty():
+		return a
+	case a.hi <= x0:
+		return a.decreasing(f)
+	case a.lo >= x0:
+		return a.increasing(f)
+	}
+
+	return bounds(f(x0), max(f(a.lo), f(a.hi)), a.def, a.cont)
+}
+
+// gammaMin is where Γ has its minimum over the positive numbers.
+const gammaMin = 1.4616321449683623
+
+// gammaInterval encloses Γ, or log |Γ| if log. Between the poles at 0 and
+// the negative integers log |Γ| is convex, so both are valleys in absolute
+// value, Γ being negative between -2n-1 and -2n.
+func gammaInterval(a interval, log bool) interval {
+	f := math.Gamma
+	if log {
+		f = lgamma
+	}
+	if a.lo > 0 {
+		return a.valley(f, gammaMin)
+	}
+
+	k := math.Ceil(a.lo)
+	if k <= a.hi || k < -1<<20 {
+		return entireInterval()
+	}
+
+	n := -k
+	if log {
+		return a.valley(f, gammaValley(n))
+	}
+	r := a.valley(func(x float64) float64 { return math.Abs(math.Gamma(x)) }, gammaValley(n))
+	if math.Mod(n, 2) == 0 {
+		return r.neg()
+	}
+
+	return r
+}
+
+// gammaValleys are where |Γ| is smallest between the first poles -n-1 and
+// -n.
+var gammaValleys = func() []float64 {
+	v := make([]float64, 64)
+	for n := range v {
+		v[n] = findGammaValley(float64(n))
+	}
+	return v
+}()
+
+func gammaValley(n float64) float64 {
+	if n < float64(len(gammaValleys)) {
+		return gammaValleys[int(n)]
+	}
+
+	return findGammaValley(n)
+}
+
+// findGammaValley finds where log |Γ| is smallest between -n-1 and -n, by
+// golden section search.
+func findGammaValley(n float64) float64 {
+	const g = 0.6180339887498949 // (√5 - 1) / 2
+	a, b := -n-1, -n
+	for range 80 {
+		c, d := b-g*(b-a), a+g*(b-a)
+		if lgamma(c) < lgamma(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+
+	return (a + b) / 2
+}
+
+// loose is bounds for the functions computed by series and iterations,
+// which are off by more than an ulp.
+func loose(lo, hi float64, def, cont bool) interval {
+	const eps = 1e-9
+	return bounds(lo-eps*math.Abs(lo), hi+eps*math.Abs(hi), def, cont)
+}
+
+// monotone encloses f, increasing in the arguments whose dir is 1 and
+// decreasing in those whose dir is -1, by its values at two corners of the
+// box.
+func monotone(f func(v []float64) float64, a []interval, dirs ...int) interval {
+	lo, hi := make([]float64, len(a)), make([]float64, len(a))
+	def, cont, point := true, true, true
+	for i, v := range a {
+		if v.empty() {
+			return emptyInterval()
+		}
+		lo[i], hi[i] = v.lo, v.hi
+		if dirs[i] < 0 {
+			lo[i], hi[i] = v.hi, v.lo
+		}
+		def, cont, point = def && v.def, cont && v.cont, point && v.point()
+	}
+
+	if point {
+		v := f(lo)
+		if math.IsNaN(v) {
+			return emptyInterval()
+		}
+		return loose(v, v, def, cont)
+	}
+
+	return loose(f(lo), f(hi), def, cont)
+}
+
+// stepped makes r discontinuous where k, which the function floors, goes
+// across an integer.
+func stepped(r, k interval) interval {
+	if math.Floor(k.lo) != math.Floor(k.hi) {
+		r.cont = false
+	}
+
+	return r
+}
+
+// split encloses a function over a by its enclosures f below and above c.
+func (a interval) split(c float64, f func(part interval) interval) interval {
+	if a.empty() || a.hi <= c || a.lo >= c {
+		return f(a)
+	}
+
+	lo, hi := f(interval{a.lo, c, a.def, a.cont}), f(interval{c, a.hi, a.def, a.cont})
+	r := hull(lo, hi)
+	r.def, r.cont = lo.def && hi.def, lo.cont && hi.cont
+
+	return r
+}
+
+// positive and probability restrict a to (0, ∞) and (0, 1), the domains
+// of the degrees of freedom and of the inverse cdfs.
+func (a interval) positive() interval {
+	return a.restrict(math.SmallestNonzeroFloat64, math.Inf(1))
+}
+
+func (a interval) probability() interval {
+	return a.restrict(math.SmallestNonzeroFloat64, math.Nextafter(1, 0))
+}
+
+// standardize is (x - mu) / sigma.
+func standardize(x, mu, sigma interval) interval {
+	return x.sub(mu).div(sigma)
+}
+
+// maxIntegers is how many integers integers encloses a function at, one
+// by one.
+const maxIntegers = 64
+
+// integers encloses a function of k that is 0 but at the non-negative
+// integers, by f at those in k, or by bound if there are more than
+// maxIntegers of them.
+func integers(k, bound interval, f func(k float64) interval) interval {
+	lo, hi := math.Ceil(max(k.lo, 0)), math.Floor(k.hi)
+	if k.point() {
+		if lo != hi {
+			return interval{0, 0, k.def, k.cont}
+		}
+		r := f(lo)
+		r.def, r.cont = r.def && k.def, r.cont && k.cont
+		return r
+	}
+
+	if hi-lo >= maxIntegers {
+		return interval{min(bound.lo, 0), max(bound.hi, 0), k.def && bound.def, false}
+	}
+
+	r := interval{0, 0, k.def, false}
+	for i := lo; i <= hi; i++ {
+		v := f(i)
+		if v.empty() {
+			r.def = false
+			continue
+		}
+		r = interval{min(r.lo, v.lo), max(r.hi, v.hi), r.def && v.def, false}
+	}
+
+	return r
+}
+
+// intervalFunctions enclose the functions of the same name. Those missing
+// can't be drawn in intervalMode.
+var intervalFunctions = map[string]intervalFunction{
+	"sqrt":  unaryInterval(func(a interval) interval { return a.restrict(0, math.Inf(1)).increasing(math.Sqrt) }),
+	"cbrt":  unaryInterval(func(a interval) interval { return a.increasing(math.Cbrt) }),
+	"abs":   unaryInterval(interval.abs),
+	"acos":  unaryInterval(func(a interval) interval { return a.restrict(-1, 1).decreasing(math.Acos) }),
+	"acosh": unaryInterval(func(a interval) interval { return a.restrict(1, math.Inf(1)).increasing(math.Acosh) }),
+	"asin":  unaryInterval(func(a interval) interval { return a.restrict(-1, 1).increasing(math.Asin) }),
+	"asinh": unaryInterval(func(a interval) interval { return a.increasing(math.Asinh) }),
+	"atan":  unaryInterval(func(a interval) interval { return a.increasing(math.Atan) }),
+	"atanh": unaryInterval(func(a interval) interval { return a.restrict(-1, 1).increasing(math.Atanh) }),
+	"ceil":  unaryInterval(func(a interval) interval { return a.steps(math.Ceil) }),
+	"floor": unaryInterval(func(a interval) interval { return a.steps(math.Floor) }),
+	"cos":   unaryInterval(func(a interval) interval { return a.periodic(math.Cos, 0) }),
+	"sin":   unaryInterval(func(a interval) interval { return a.periodic(math.Sin, math.Pi/2) }),
+	"tan":   unaryInterval(interval.tan),
+	"exp":   unaryInterval(func(a interval) interval { return a.increasing(math.Exp) }),
+	"log":   unaryInterval(func(a interval) interval { return a.restrict(0, math.Inf(1)).increasing(math.Log) }),
+	"log2":  unaryInterval(func(a interval) interval { return a.restrict(0, math.Inf(1)).increasing(math.Log2) }),
+	"log10": unaryInterval(func(a interval) interval { return a.restrict(0, math.Inf(1)).increasing(math.Log10) }),
+	"cosh":  unaryInterval(func(a interval) interval { return a.valley(math.Cosh, 0) }),
+	"sinh":  unaryInterval(func(a interval) interval { return a.increasing(math.Sinh) }),
+	"tanh":  unaryInterval(func(a interval) interval { return a.increasing(math.Tanh) }),
+	"erf":   unaryInterval(func(a interval) interval { return a.increasing(math.Erf) }),
+	"erfc":  unaryInterval(func(a interval) interval { return a.decreasing(math.Erfc) }),
+
+	"gamma":  unaryInterval(func(a interval) interval { return gammaInterval(a, false) }),
+	"lgamma": unaryInterval(func(a interval) interval { return gammaInterval(a, true) }),
+	"beta": binaryInterval(func(a, b interval) interval {
+		if a.lo > 0 && b.lo > 0 {
+			// decreasing in both over the positive numbers
+			return monotone(func(v []float64) float64 { return beta(v[0], v[1]) }, []interval{a, b}, -1, -1)
+		}
+		// |Γ(a)·Γ(b)/Γ(a+b)|, as beta computes it
+		return gammaInterval(a, true).add(gammaInterval(b, true)).sub(gammaInterval(a.add(b), true)).increasing(math.Exp)
+	}),
+	"factorial": unaryInterval(func(a interval) interval {
+		// defined at the non-negative integers only, where it increases
+		lo, hi := math.Ceil(max(a.lo, 0)), math.Floor(a.hi)
+		if a.empty() || lo > hi {
+			return emptyInterval()
+		}
+		return bounds(factorial(lo), factorial(hi), a.point() && a.def, a.point() && a.cont)
+	}),
+	"nCr": binaryInterval(func(n, k interval) interval {
+		top := math.Floor(n.hi)
+		return integers(n, bounds(0, choose(top, math.Floor(top/2)), true, false), func(n float64) interval {
+			// at most the middle of the row of Pascal's triangle
+			return integers(k, bounds(0, choose(n, math.Floor(n/2)), true, false), func(k float64) interval {
+				return pointInterval(choose(n, k))
+			})
+		})
+	}),
+	"nPr": binaryInterval(func(n, k interval) interval {
+		return integers(n, bounds(0, factorial(math.Floor(n.hi)), true, false), func(n float64) interval {
+			return integers(k, bounds(0, factorial(n), true, false), func(k float64) interval {
+				return pointInterval(permutations(n, k))
+			})
+		})
+	}),
+	"normalpdf": nInterval(func(a []interval) interval {
+		if len(a) == 1 {
+			return a[0].abs().decreasing(normalPDF)
+		}
+		return standardize(a[0], a[1], a[2]).abs().decreasing(normalPDF).div(a[2])
+	}, 1, 3),
+	"normalcdf": nInterval(func(a []interval) interval {
+		switch len(a) {
+		case 1:
+			return a[0].increasing(normalCDF)
+		case 3:
+			return standardize(a[0], a[1], a[2]).increasing(normalCDF)
+		}
+		return standardize(a[1], a[2], a[3]).increasing(normalCDF).sub(standardize(a[0], a[2], a[3]).increasing(normalCDF))
+	}, 1, 3, 4),
+	"invnorm": nInterval(func(a []interval) interval {
+		z := a[0].restrict(0, 1).increasing(func(p float64) float64 { return math.Sqrt2 * math.Erfinv(2*p-1) })
+		if len(a) == 1 {
+			return z
+		}
+		return a[1].add(a[2].mul(z))
+	}, 1, 3),
+	"binompdf": nInterval(func(a []interval) interval {
+		n, p, k := a[0], a[1], a[2]
+		bound := interval{math.Inf(-1), math.Inf(1), n.def && p.def && k.def, false}
+		if p.lo >= 0 && p.hi <= 1 {
+			bound = interval{0, 1, bound.def, false}
+		}
+		return integers(n, bound, func(n float64) interval {
+			return integers(k, bound, func(k float64) interval {
+				if k > n {
+					return pointInterval(0)
+				}
+				// C(n, k)·p^k·(1-p)^(n-k)
+				q := pointInterval(1).sub(p)
+				return pointInterval(choose(n, k)).mul(p.pow(pointInterval(k))).mul(q.pow(pointInterval(n - k)))
+			})
+		})
+	}, 3),
+	"binomcdf": nInterval(func(a []interval) interval {
+		n, p, k := a[0], a[1], a[2]
+		f := func(v []float64) float64 { return binomialCDF(v[0], v[1], v[2]) }
+		r := stepped(monotone(f, []interval{n, p.restrict(0, 1), k}, -1, -1, 1), k)
+		if p.lo < 0 || p.hi > 1 {
+			// which doesn't matter below k = 0 and from k = n up
+			if math.Floor(k.lo) < 0 {
+				r = hull(r, pointInterval(0))
+			}
+			if math.Floor(k.hi) >= n.lo {
+				r = hull(r, pointInterval(1))
+			}
+		}
+		return r
+	}, 3),
+	"poissonpdf": nInterval(func(a []interval) interval {
+		lambda, k := a[0].restrict(0, math.Inf(1)), a[1]
+		log := lambda.increasing(math.Log)
+		return integers(k, interval{0, 1, lambda.def && k.def, false}, func(k float64) interval {
+			// e^(k·log λ - λ - log k!)
+			return pointInterval(k).mul(log).sub(lambda).sub(pointInterval(lgamma(k + 1))).increasing(math.Exp)
+		})
+	}, 2),
+	"poissoncdf": nInterval(func(a []interval) interval {
+		f := func(v []float64) float64 { return poissonCDF(v[0], v[1]) }
+		r := stepped(monotone(f, []interval{a[0].restrict(0, math.Inf(1)), a[1]}, -1, 1), a[1])
+		if a[0].lo < 0 && math.Floor(a[1].lo) < 0 {
+			// which is 0 below k = 0 whatever λ
+			r = hull(r, pointInterval(0))
+		}
+		return r
+	}, 2),
+	"tpdf": nInterval(func(a []interval) interval {
+		x, v := a[0], a[1].positive()
+		if v.empty() {
+			return v
+		}
+		if x.point() && v.point() {
+			return pointFunc(tPDF(x.lo, v.lo), interval{def: x.def && v.def, cont: x.cont && v.cont})
+		}
+		// c(v)·(1 + x²/v)^(-(v+1)/2), the constant c increasing with v
+		c := monotone(func(v []float64) float64 { return tPDF(0, v[0]) }, []interval{v}, 1)
+		one := pointInterval(1)
+		return c.mul(one.add(x.pow(pointInterval(2)).div(v)).pow(v.add(one).mul(pointInterval(-0.5))))
+	}, 2),
+	"tcdf": nInterval(func(a []interval) interval {
+		f := func(v []float64) float64 { return tCDF(v[0], v[1]) }
+		v := a[1].positive()
+		return a[0].split(0, func(x interval) interval {
+			// heavier tails for fewer degrees of freedom
+			if x.hi <= 0 {
+				return monotone(f, []interval{x, v}, 1, -1)
+			}
+			return monotone(f, []interval{x, v}, 1, 1)
+		})
+	}, 2),
+	"invt": nInterval(func(a []interval) interval {
+		f := func(v []float64) float64 {
+			return invertCDF(func(x float64) float64 { return tCDF(x, v[1]) }, v[0], -10, 10)
+		}
+		v := a[1].positive()
+		return a[0].probability().split(0.5, func(p interval) interval {
+			if p.hi <= 0.5 {
+				return monotone(f, []interval{p, v}, 1, 1)
+			}
+			return monotone(f, []interval{p, v}, 1, -1)
+		})
+	}, 2),
+	"chisqpdf": nInterval(func(a []interval) interval {
+		k := a[1]
+		// over x ≥ 0
+		density := func(x interval) interval {
+			if x.point() && k.point() {
+				return pointFunc(chiSquaredPDF(x.lo, k.lo), interval{def: x.def && k.def, cont: x.cont && k.cont})
+			}
+			// e^((k/2-1)·log x - x/2 - k/2·log 2 - log Γ(k/2))
+			half := k.mul(pointInterval(0.5))
+			l := half.sub(pointInterval(1)).mul(x.increasing(math.Log))
+			l = l.sub(x.mul(pointInterval(0.5))).sub(half.mul(pointInterval(math.Ln2))).sub(gammaInterval(half, true))
+			return l.increasing(math.Exp)
+		}
+		return a[0].split(0, func(x interval) interval {
+			zero := interval{0, 0, x.def && k.def, x.cont && k.cont}
+			switch {
+			case x.hi < 0:
+				return zero
+			case x.lo < 0:
+				// 0 up to the density at 0
+				d := density(interval{0, 0, x.def, x.cont})
+				r := hull(zero, d)
+				r.def = zero.def && d.def
+				return r
+			}
+			return density(x)
+		})
+	}, 2),
+	"chisqcdf": nInterval(func(a []interval) interval {
+		f := func(v []float64) float64 { return chiSquaredCDF(v[0], v[1]) }
+		k := a[1].positive()
+		return a[0].split(0, func(x interval) interval {
+			zero := interval{0, 0, x.def && a[1].def, x.cont && a[1].cont}
+			switch {
+			case x.hi <= 0:
+				return zero
+			case x.lo <= 0 && a[1].lo <= 0:
+				// 0 at x = 0 whatever k
+				return hull(zero, monotone(f, []interval{x, k}, 1, -1))
+			}
+			return monotone(f, []interval{x, k}, 1, -1)
+		})
+	}, 2),
+	"invchisq": nInterval(func(a []interval) interval {
+		f := func(v []float64) float64 {
+			return invertCDF(func(x float64) float64 { return chiSquaredCDF(x, v[1]) }, v[0], 0, 2*v[1]+10)
+		}
+		return monotone(f, []interval{a[0].probability(), a[1].positive()}, 1, 1)
+	}, 2),
+
+	"min": binaryInterval(func(a, b interval) interval {
+		return interval{min(a.lo, b.lo), min(a.hi, b.hi), a.def && b.def, a.cont && b.cont}
+	}),
+	"max": binaryInterval(func(a, b interval) interval {
+		return interval{max(a.lo, b.lo), max(a.hi, b.hi), a.def && b.def, a.cont && b.cont}
+	}),
+	"dim": binaryInterval(func(a, b interval) interval {
+		d := a.sub(b)
+		return interval{max(d.lo, 0), max(d.hi, 0), d.def, d.cont}
+	}),
+	"hypot": binaryInterval(func(a, b interval) interval {
+		a, b = a.abs(), b.abs()
+		return bounds(math.Hypot(a.lo, b.lo), math.Hypot(a.hi, b.hi), a.def && b.def, a.cont && b.cont)
+	}),
+	"copysign": binaryInterval(func(a, b interval) interval {
+		m := a.abs()
+		switch {
+		case b.lo > 0:
+			return interval{m.lo, m.hi, m.def && b.def, m.cont && b.cont}
+		case b.hi < 0:
+			return interval{-m.hi, -m.lo, m.def && b.def, m.cont && b.cont}
+		}
+		return interval{-m.hi, m.hi, m.def && b.def, false}
+	}),
+	"atan2":     binaryInterval(atan2Interval),
+	"mod":       binaryInterval(func(a, b interval) interval { return a.mod(b, false) }),
+	"remainder": binaryInterval(func(a, b interval) interval { return a.mod(b, true) }),
+
+	"sum":   listInterval(func(l []interval) interval { return sumIntervals(l) }),
+	"mean":  listInterval(func(l []interval) interval { return sumIntervals(l).div(pointInterval(float64(len(l)))) }),
+	"count": listInterval(func(l []interval) interval { return pointInterval(float64(len(l))) }),
+	"var":   listInterval(varianceInterval),
+	"stdev": listInterval(func(l []interval) interval {
+		v := varianceInterval(l)
+		v.lo = max(v.lo, 0)
+		return v.increasing(math.Sqrt)
+	}),
+	"median": listInterval(func(l []interval) interval {
+		// quantiles increase with each of the values
+		if len(l) == 0 {
+			return emptyInterval()
+		}
+		return quantileInterval(l, 0.5)
+	}),
+	"quantile": listInterval(func(l []interval) interval {
+		if len(l) < 2 {
+			return emptyInterval()
+		}
+		// and with p
+		p := l[0].restrict(0, 1)
+		if p.empty() {
+			return p
+		}
+		lo, hi := quantileInterval(l[1:], p.lo), quantileInterval(l[1:], p.hi)
+		return interval{lo.lo, hi.hi, lo.def && p.def, lo.cont && p.cont}
+	}),
+
+	// random values are anywhere in their range, from one point to the
+	// next
+	"rnd": listInterval(func(l []interval) interval { return interval{0, 1, true, false} }),
+	"rndint": binaryInterval(func(a, b interval) interval {
+		return interval{math.Ceil(a.lo), math.Floor(b.hi), false, false}
+	}),
+}
+
+// intervalFunction is an interval version of a function of one of arities
+// numbers of arguments, or of a list if there are none.
+type intervalFunction struct {
+	arities []int
+	f       func(a []interval) interval
+}
+
+func unaryInterval(f func(a interval) interval) intervalFunction {
+	return intervalFunction{[]int{1}, func(a []interval) interval {
+		if a[0].empty() {
+			return emptyInterval()
+		}
+		return f(a[0])
+	}}
+}
+
+func binaryInterval(f func(a, b interval) interval) intervalFunction {
+	return intervalFunction{[]int{2}, func(a []interval) interval {
+		if a[0].empty() || a[1].empty() {
+			return emptyInterval()
+		}
+		return f(a[0], a[1])
+	}}
+}
+
+func listInterval(f func(l []interval) interval) intervalFunction {
+	return nInterval(f)
+}
+
+// nInterval is an interval version of a function of one of arities numbers
+// of arguments.
+func nInterval(f func(a []interval) interval, arities ...int) intervalFunction {
+	return intervalFunction{arities, func(a []interval) interval {
+		for _, v := range a {
+			if v.empty() {
+				return emptyInterval()
+			}
+		}
+		return f(a)
+	}}
+}
+
+func sumIntervals(l []interval) interval {
+	s := pointInterval(0)
+	for _, v := range l {
+		s = s.add(v)
+	}
+
+	return s
+}
+
+// varianceInterval encloses the sample variance as the sum of the squared
+// differences of the pairs over n(n-1), which unlike the deviations from
+// the mean doesn't depend on each value twice.
+func varianceInterval(l []interval) interval {
+	if len(l) < 2 {
+		return emptyInterval()
+	}
+
+	s := pointInterval(0)
+	for i := range l {
+		for j := i + 1; j < len(l); j++ {
+			s = s.add(l[i].sub(l[j]).pow(pointInterval(2)))
+		}
+	}
+
+	return s.div(pointInterval(float64(len(l) * (len(l) - 1))))
+}
+
+func quantileInterval(l []interval, p float64) interval {
+	los, his := make([]float64, len(l)), make([]float64, len(l))
+	def, cont := true, true
+	for i, v := range l {
+		los[i], his[i] = v.lo, v.hi
+		def, cont = def && v.def, cont && v.cont
+	}
+
+	return bounds(quantile(los, p), quantile(his, p), def, cont)
+}
+
+// atan2Interval encloses atan2(y, x) by its values at the corners of the
+// box, where the angle is extreme unless the box contains the origin or
+// crosses the cut along the negative x axis.
+func atan2Interval(y, x interval) interval {
+	def, cont := y.def && x.def, y.cont && x.cont
+	if x.lo <= 0 && y.lo <= 0 && y.hi >= 0 {
+		if x.hi >= 0 || y.lo < 0 {
+			return interval{-math.Pi, math.Pi, def, false}
+		}
+	}
+
+	p := []float64{math.Atan2(y.lo, x.lo), math.Atan2(y.lo, x.hi), math.Atan2(y.hi, x.lo), math.Atan2(y.hi, x.hi)}
+
+	return bounds(slices.Min(p), slices.Max(p), def, cont)
+}
+
+// intervalFunc evaluates an expression over the box x, y.
+type intervalFunc func(x, y interval) interval
+
+// compileInterval compiles n for the interval evaluation. bound are the
+// variables bound by the sums and products n is inside of.
+func compileInterval(n *node, bound map[string]*interval) (intervalFunc, error) {
+	if n.op == "call" && (n.name == "summation" || n.name == "product") && len(n.args) == 4 && n.args[1].op == "var" {
+		return compileIntervalSeries(n, bound)
+	}
+
+	args := make([]intervalFunc, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], err = compileInterval(a, bound); err != nil {
+			return nil, err
+		}
+	}
+
+	constant := func(v float64) (intervalFunc, error) {
+		r := pointInterval(v)
+		if float64(int64(v)) != v {
+			// the constant itself is rounded
+			r = bounds(v, v, true, true)
+		}
+		return func(x, y interval) interval { return r }, nil
+	}
+
+	switch n.op {
+	case "num":
+		return constant(n.value)
+	case "var":
+		if v, ok := bound[n.name]; ok {
+			return func(x, y interval) interval { return *v }, nil
+		}
+		switch n.name {
+		case "x":
+			return func(x, y interval) interval { return x }, nil
+		case "y":
+			return func(x, y interval) interval { return y }, nil
+		case "π", "pi":
+			return constant(math.Pi)
+		case "e":
+			return constant(math.E)
+		case "max64":
+			return constant(math.MaxFloat64)
+		case "min64":
+			return constant(math.SmallestNonzeroFloat64)
+		}
+
+		return nil, errors.New("unknown variable '" + n.name + "'")
+	case "neg":
+		a := args[0]
+		return func(x, y interval) interval { return a(x, y).neg() }, nil
+	case "call":
+		f, ok := intervalFunctions[n.name]
+		if !ok {
+			if _, ok := functions[n.name]; ok {
+				// such as the random and noise functions, which can't be
+				// enclosed better than by anything
+				return nil, errors.New("'" + n.name + "' isn't defined with intervals")
+			}
+			return nil, errors.New("unknown function '" + n.name + "'")
+		}
+		if len(f.arities) > 0 && !slices.Contains(f.arities, len(args)) {
+			counts := make([]string, len(f.arities))
+			for i, c := range f.arities {
+				counts[i] = strconv.Itoa(c)
+			}
+			return nil, errors.New("'" + n.name + "' must have " + strings.Join(counts, " or ") + " arguments")
+		}
+		return func(x, y interval) interval {
+			values := make([]interval, len(args))
+			for i, a := range args {
+				values[i] = a(x, y)
+			}
+			return f.f(values)
+		}, nil
+	case "?":
+		c, a, b := args[0], args[1], args[2]
+		return func(x, y interval) interval {
+			v := c(x, y)
+			if v.empty() {
+				return v
+			}
+			switch holds, fails := v.truth(); {
+			case holds:
+				r := a(x, y)
+				r.def = r.def && v.def
+				return r
+			case fails:
+				r := b(x, y)
+				r.def = r.def && v.def
+				return r
+			}
+			return hull(a(x, y), b(x, y))
+		}, nil
+	case "!":
+		a := args[0]
+		return func(x, y interval) interval {
+			v := a(x, y)
+			holds, fails := v.truth()
+			return truthInterval(fails, holds, v, v)
+		}, nil
+	}
+
+	op, ok := intervalOperators[n.op]
+	if !ok {
+		return nil, errors.New("'" + n.op + "' isn't defined with intervals")
+	}
+	a, b := args[0], args[1]
+
+	return func(x, y interval) interval { return op(a(x, y), b(x, y)) }, nil
+}
+
+var intervalOperators = map[string]func(a, b interval) interval{
+	"+": interval.add,
+	"-": interval.sub,
+	"*": interval.mul,
+	"/": interval.div,
+	"%": func(a, b interval) interval { return a.mod(b, false) },
+	"^": interval.pow,
+	"<": func(a, b interval) interval { return truthInterval(a.hi < b.lo, a.lo >= b.hi, a, b) },
+	"<=": func(a, b interval) interval {
+		return truthInterval(a.hi <= b.lo, a.lo > b.hi, a, b)
+	},
+	">": func(a, b interval) interval { return truthInterval(a.lo > b.hi, a.hi <= b.lo, a, b) },
+	">=": func(a, b interval) interval {
+		return truthInterval(a.lo >= b.hi, a.hi < b.lo, a, b)
+	},
+	"==": func(a, b interval) interval {
+		return truthInterval(a.point() && b.point() && a.lo == b.lo, a.hi < b.lo || a.lo > b.hi, a, b)
+	},
+	"!=": func(a, b interval) interval {
+		return truthInterval(a.hi < b.lo || a.lo > b.hi, a.point() && b.point() && a.lo == b.lo, a, b)
+	},
+	"&&": func(a, b interval) interval {
+		ha, fa := a.truth()
+		hb, fb := b.truth()
+		return truthInterval(ha && hb, fa || fb, a, b)
+	},
+	"||": func(a, b interval) interval {
+		ha, fa := a.truth()
+		hb, fb := b.truth()
+		return truthInterval(ha || hb, fa && fb, a, b)
+	},
+}
+
+// compileIntervalSeries compiles summation(expr, k, a, b) and
+// product(expr, k, a, b), which are enclosed term by term when their bounds
+// are known exactly.
+func compileIntervalSeries(n *node, bound map[string]*interval) (intervalFunc, error) {
+	k := new(interval)
+	inner := map[string]*interval{n.args[1].name: k}
+	for name, v := range bound {
+		if _, ok := inner[name]; !ok {
+			inner[name] = v
+		}
+	}
+
+	body, err := compileInterval(n.args[0], inner)
+	if err != nil {
+		return nil, err
+	}
+	from, err := compileInterval(n.args[2], bound)
+	if err != nil {
+		return nil, err
+	}
+	to, err := compileInterval(n.args[3], bound)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, op := pointInterval(0), interval.add
+	if n.name == "product" {
+		identity, op = pointInterval(1), interval.mul
+	}
+
+	return func(x, y interval) interval {
+		a, b := from(x, y), to(x, y)
+		if a.empty() || b.empty() {
+			return emptyInterval()
+		}
+		// the terms change across the box with the bounds
+		lo, hi := math.Ceil(a.lo), math.Floor(b.lo)
+		if lo != math.Ceil(a.hi) || hi != math.Floor(b.hi) {
+			return entireInterval()
+		}
+		if hi-lo >= maxTerms {
+			return emptyInterval()
+		}
+
+		acc := identity
+		for i := lo; i <= hi; i++ {
+			*k = pointInterval(i)
+			acc = op(acc, body(x, y))
+		}
+
+		return acc
+	}, nil
+}
+
+// intervalPlot is a relation between two expressions of x and y, such as
+// x^2 + y^2 = 1 or y < sin(x), drawn with interval arithmetic.
+type intervalPlot struct {
+	// f is the left hand side minus the right hand side, compared to 0
+	// with op.
+	f  intervalFunc
+	op string
+}
+
+// relationOperators are the comparisons intervalPlot draws.
+var relationOperators = []string{"<=", ">=", "!=", "==", "<", ">", "="}
+
+// cutRelation cuts str around its comparison at the top level: the only
+// = outside of <=, >=, != and ==, or else the comparison at the root of
+// the expression.
+func cutRelation(str string) (lhs, rhs *node, op string, ok bool) {
+	depth := 0
+	eq := -1
+	for i, c := range str {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '=':
+			if depth > 0 || i > 0 && strings.ContainsRune("<>!=", rune(str[i-1])) || i+1 < len(str) && str[i+1] == '=' {
+				continue
+			}
+			if eq >= 0 {
+				return nil, nil, "", false
+			}
+			eq = i
+		}
+	}
+
+	if eq >= 0 {
+		var err error
+		if lhs, err = parseExpr(str[:eq]); err != nil {
+			return nil, nil, "", false
+		}
+		if rhs, err = parseExpr(str[eq+1:]); err != nil {
+			return nil, nil, "", false
+		}
+		return lhs, rhs, "=", true
+	}
+
+	n, err := parseExpr(str)
+	if err != nil || !slices.Contains(relationOperators, n.op) {
+		return nil, nil, "", false
+	}
+
+	return n.args[0], n.args[1], n.op, true
+}
+
+// isRelation reports whether str is an equation or inequality in x and y.
+func isRelation(str string) bool {
+	_, _, _, ok := cutRelation(str)
+	return ok
+}
+
+func parseIntervalPlot(str string) (*intervalPlot, error) {
+	lhs, rhs, op, ok := cutRelation(str)
+	if !ok {
+		return nil, errors.New("expected an equation or an inequality")
+	}
+
+	f, err := compileInterval(&node{op: "-", args: []*node{lhs, rhs}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if op == "==" {
+		op = "="
+	}
+
+	return &intervalPlot{f: f, op: op}, nil
+}
+
+// truth of a relation over a box.
+type truth int
+
+const (
+	excluded truth = iota
+	proven
+	undetermined
+)
+
+// test reports whether the relation holds over the whole box, nowhere in
+// it or neither.
+func (r *intervalPlot) test(v interval) truth {
+	if v.empty() {
+		return excluded
+	}
+
+	var holds, fails bool
+	switch r.op {
+	case "=":
+		holds, fails = v.lo == 0 && v.hi == 0, v.lo > 0 || v.hi < 0
+	case "!=":
+		holds, fails = v.lo > 0 || v.hi < 0, v.lo == 0 && v.hi == 0
+	case "<":
+		holds, fails = v.hi < 0, v.lo >= 0
+	case "<=":
+		holds, fails = v.hi <= 0, v.lo > 0
+	case ">":
+		holds, fails = v.lo > 0, v.hi <= 0
+	case ">=":
+		holds, fails = v.lo >= 0, v.hi < 0
+	}
+
+	switch {
+	case fails:
+		return excluded
+	case holds && v.def:
+		return proven
+	}
+
+	return undetermined
+}
+
+// box is the interval of x and y over the image rectangle from px0, py0 to
+// px1, py1.
+func pixelBox(px0, py0, px1, py1 float64) (x, y interval) {
+	x0, y1 := fromPixel(px0, py0)
+	x1, y0 := fromPixel(px1, py1)
+
+	return interval{x0, x1, true, true}, interval{y0, y1, true, true}
+}
+
+// refine decides a pixel, or part of one, the relation is undetermined
+// over: proven if it holds at one of its points, or if it is an equation
+// whose continuous left hand side changes sign, excluded if it is
+// excluded from all its quarters. The quarters are refined down to a
+// sixteenth of a pixel.
+func (r *intervalPlot) refine(px0, py0, px1, py1 float64, depth int) truth {
+	x, y := pixelBox(px0, py0, px1, py1)
+	v := r.f(x, y)
+	if t := r.test(v); t != undetermined {
+		return t
+	}
+
+	var positive, negative bool
+	for _, p := range [][2]float64{{px0, py0}, {px1, py0}, {px0, py1}, {px1, py1}, {(px0 + px1) / 2, (py0 + py1) / 2}} {
+		x, y := fromPixel(p[0], p[1])
+		w := r.f(pointInterval(x), pointInterval(y))
+		if r.test(w) == proven {
+			return proven
+		}
+		positive = positive || w.def && w.lo > 0
+		negative = negative || w.def && w.hi < 0
+	}
+	if r.op == "=" && positive && negative && v.def && v.cont {
+		return proven
+	}
+
+	if depth == 2 {
+		return undetermined
+	}
+
+	mx, my := (px0+px1)/2, (py0+py1)/2
+	t := excluded
+	for _, q := range [][4]float64{{px0, py0, mx, my}, {mx, py0, px1, my}, {px0, my, mx, py1}, {mx, my, px1, py1}} {
+		switch r.refine(q[0], q[1], q[2], q[3], depth+1) {
+		case proven:
+			return proven
+		case undetermined:
+			t = undetermined
+		}
+	}
+
+	return t
+}
+
+// draw subdivides the image from blocks of 16 pixels down to pixels,
+// leaving out the blocks the relation provably doesn't hold anywhere in.
+// Inequalities shade their regions, the pixels left undetermined being
+// hatched.
+func (r *intervalPlot) draw(c color.Color) {
+	on, unknown := c, fade(c)
+	if r.op != "=" {
+		on = fade(c)
+	}
+
+	set := func(px, py int, t truth) {
+		switch {
+		case t == proven:
+			graph.Set(px, py, on)
+		case t == undetermined && (r.op == "=" || (px+py)%2 == 0):
+			graph.Set(px, py, unknown)
+		}
+	}
+
+	var block func(px, py, w, h int)
+	block = func(px, py, w, h int) {
+		x, y := pixelBox(float64(px), float64(py), float64(px+w), float64(py+h))
+		t := r.test(r.f(x, y))
+		switch {
+		case t == excluded:
+			return
+		case w == 1 && h == 1:
+			if t == undetermined {
+				t = r.refine(float64(px), float64(py), float64(px+1), float64(py+1), 0)
+			}
+			set(px, py, t)
+			return
+		case t == proven:
+			for i := px; i < px+w; i++ {
+				for j := py; j < py+h; j++ {
+					set(i, j, t)
+				}
+			}
+			return
+		}
+
+		w0, h0 := max(w/2, 1), max(h/2, 1)
+		block(px, py, w0, h0)
+		if w > w0 {
+			block(px+w0, py, w-w0, h0)
+		}
+		if h > h0 {
+			block(px, py+h0, w0, h-h0)
+		}
+		if w > w0 && h > h0 {
+			block(px+w0, py+h0, w-w0, h-h0)
+		}
+	}
+
+	const size = 16
+	for py := 0; py < graph.Rect.Dy(); py += size {
+		for px := 0; px < graph.Rect.Dx(); px += size {
+			block(px, py, min(size, graph.Rect.Dx()-px), min(size, graph.Rect.Dy()-py))
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// call calls the function name of expressions at numbers.
+func call(name string, args ...float64) float64 {
+	a := make([]interface{}, len(args))
+	for i, v := range args {
+		a[i] = v
+	}
+
+	v, err := functions[name](a...)
+	if f, ok := v.(float64); ok && err == nil {
+		return f
+	}
+
+	return math.NaN()
+}
+
+// truthValue is a comparison as intervals compute it, 1 or 0.
+func truthValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// TestIntervalEnclosures checks that the interval version of each operator
+// and function contains its value at random points of random boxes, and
+// that the function is defined over the boxes the intervals say it is.
+func TestIntervalEnclosures(t *testing.T) {
+	tests := []struct {
+		expr string
+		f    func(x, y float64) float64
+
+		// the boxes are drawn within these ranges
+		x, y [2]float64
+	}{
+		{"x + y", func(x, y float64) float64 { return x + y }, [2]float64{-10, 10}, [2]float64{-10, 10}},
+		{"x - y", func(x, y float64) float64 { return x - y }, [2]float64{-10, 10}, [2]float64{-10, 10}},
+		{"x * y", func(x, y float64) float64 { return x * y }, [2]float64{-10, 10}, [2]float64{-10, 10}},
+		{"x / y", func(x, y float64) float64 { return x / y }, [2]float64{-10, 10}, [2]float64{-2, 2}},
+		{"x % y", func(x, y float64) float64 { return math.Mod(x, y) }, [2]float64{-10, 10}, [2]float64{-3, 3}},
+		{"x ^ y", func(x, y float64) float64 { return math.Pow(x, y) }, [2]float64{-2, 4}, [2]float64{-3, 3}},
+		{"x ^ 3", func(x, y float64) float64 { return x * x * x }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"x ^ 2", func(x, y float64) float64 { return x * x }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"x ^ -2", func(x, y float64) float64 { return 1 / (x * x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"-x", func(x, y float64) float64 { return -x }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"x < y", func(x, y float64) float64 { return truthValue(x < y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x <= y", func(x, y float64) float64 { return truthValue(x <= y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x > y", func(x, y float64) float64 { return truthValue(x > y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x >= y", func(x, y float64) float64 { return truthValue(x >= y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x == y", func(x, y float64) float64 { return truthValue(x == y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x != y", func(x, y float64) float64 { return truthValue(x != y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x > 0 && y > 0", func(x, y float64) float64 { return truthValue(x > 0 && y > 0) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x > 0 || y > 0", func(x, y float64) float64 { return truthValue(x > 0 || y > 0) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"!(x > y)", func(x, y float64) float64 { return truthValue(!(x > y)) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"x > y ? x : y", func(x, y float64) float64 { return math.Max(x, y) }, [2]float64{-1, 1}, [2]float64{-1, 1}},
+		{"summation(x*k, k, 1, 4)", func(x, y float64) float64 { return 10 * x }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"product(x+k, k, 1, 3)", func(x, y float64) float64 { return (x + 1) * (x + 2) * (x + 3) }, [2]float64{-5, 2}, [2]float64{0, 0}},
+
+		{"sqrt(x)", func(x, y float64) float64 { return call("sqrt", x) }, [2]float64{-1, 4}, [2]float64{0, 0}},
+		{"cbrt(x)", func(x, y float64) float64 { return call("cbrt", x) }, [2]float64{-8, 8}, [2]float64{0, 0}},
+		{"abs(x)", func(x, y float64) float64 { return call("abs", x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"acos(x)", func(x, y float64) float64 { return call("acos", x) }, [2]float64{-1.5, 1.5}, [2]float64{0, 0}},
+		{"acosh(x)", func(x, y float64) float64 { return call("acosh", x) }, [2]float64{0, 5}, [2]float64{0, 0}},
+		{"asin(x)", func(x, y float64) float64 { return call("asin", x) }, [2]float64{-1.5, 1.5}, [2]float64{0, 0}},
+		{"asinh(x)", func(x, y float64) float64 { return call("asinh", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"atan(x)", func(x, y float64) float64 { return call("atan", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"atanh(x)", func(x, y float64) float64 { return call("atanh", x) }, [2]float64{-1.5, 1.5}, [2]float64{0, 0}},
+		{"ceil(x)", func(x, y float64) float64 { return call("ceil", x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"floor(x)", func(x, y float64) float64 { return call("floor", x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"cos(x)", func(x, y float64) float64 { return call("cos", x) }, [2]float64{-10, 10}, [2]float64{0, 0}},
+		{"sin(x)", func(x, y float64) float64 { return call("sin", x) }, [2]float64{-10, 10}, [2]float64{0, 0}},
+		{"tan(x)", func(x, y float64) float64 { return call("tan", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"exp(x)", func(x, y float64) float64 { return call("exp", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"log(x)", func(x, y float64) float64 { return call("log", x) }, [2]float64{-1, 5}, [2]float64{0, 0}},
+		{"log2(x)", func(x, y float64) float64 { return call("log2", x) }, [2]float64{-1, 5}, [2]float64{0, 0}},
+		{"log10(x)", func(x, y float64) float64 { return call("log10", x) }, [2]float64{-1, 5}, [2]float64{0, 0}},
+		{"cosh(x)", func(x, y float64) float64 { return call("cosh", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"sinh(x)", func(x, y float64) float64 { return call("sinh", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"tanh(x)", func(x, y float64) float64 { return call("tanh", x) }, [2]float64{-5, 5}, [2]float64{0, 0}},
+		{"erf(x)", func(x, y float64) float64 { return call("erf", x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"erfc(x)", func(x, y float64) float64 { return call("erfc", x) }, [2]float64{-3, 3}, [2]float64{0, 0}},
+		{"gamma(x)", func(x, y float64) float64 { return call("gamma", x) }, [2]float64{-6, 6}, [2]float64{0, 0}},
+		{"gamma(x)", func(x, y float64) float64 { return call("gamma", x) }, [2]float64{-90, -70}, [2]float64{0, 0}},
+		{"lgamma(x)", func(x, y float64) float64 { return call("lgamma", x) }, [2]float64{-6, 6}, [2]float64{0, 0}},
+		{"factorial(x)", func(x, y float64) float64 { return call("factorial", x) }, [2]float64{-2, 10}, [2]float64{0, 0}},
+		{"normalcdf(x)", func(x, y float64) float64 { return call("normalcdf", x) }, [2]float64{-4, 4}, [2]float64{0, 0}},
+		{"invnorm(x)", func(x, y float64) float64 { return call("invnorm", x) }, [2]float64{-0.2, 1.2}, [2]float64{0, 0}},
+		{"min(x, y)", func(x, y float64) float64 { return call("min", x, y) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"max(x, y)", func(x, y float64) float64 { return call("max", x, y) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"dim(x, y)", func(x, y float64) float64 { return call("dim", x, y) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"hypot(x, y)", func(x, y float64) float64 { return call("hypot", x, y) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"copysign(x, y)", func(x, y float64) float64 { return call("copysign", x, y) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"atan2(y, x)", func(x, y float64) float64 { return call("atan2", y, x) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"mod(x, y)", func(x, y float64) float64 { return call("mod", x, y) }, [2]float64{-10, 10}, [2]float64{-3, 3}},
+		{"remainder(x, y)", func(x, y float64) float64 { return call("remainder", x, y) }, [2]float64{-10, 10}, [2]float64{-3, 3}},
+		{"sum(x, y, 2)", func(x, y float64) float64 { return call("sum", x, y, 2) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"sum(x, y, 1, 2)", func(x, y float64) float64 { return x + y + 3 }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"mean(x, y, 2)", func(x, y float64) float64 { return call("mean", x, y, 2) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"count(x, y, 2)", func(x, y float64) float64 { return call("count", x, y, 2) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"var(x, y, 2)", func(x, y float64) float64 { return call("var", x, y, 2) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"stdev(x, y, 2, x)", func(x, y float64) float64 { return call("stdev", x, y, 2, x) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"median(x, y, 2)", func(x, y float64) float64 { return call("median", x, y, 2) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"quantile(x, y, 2, 1)", func(x, y float64) float64 { return call("quantile", x, y, 2, 1) }, [2]float64{-0.5, 1.5}, [2]float64{-3, 3}},
+
+		{"beta(x, y)", func(x, y float64) float64 { return call("beta", x, y) }, [2]float64{0, 4}, [2]float64{0, 4}},
+		{"beta(x, y)", func(x, y float64) float64 { return call("beta", x, y) }, [2]float64{-4, 4}, [2]float64{-4, 4}},
+		{"nCr(x, y)", func(x, y float64) float64 { return call("nCr", x, y) }, [2]float64{-2, 30}, [2]float64{-2, 30}},
+		{"nCr(x, 3)", func(x, y float64) float64 { return call("nCr", x, 3) }, [2]float64{-2, 300}, [2]float64{0, 0}},
+		{"nPr(x, y)", func(x, y float64) float64 { return call("nPr", x, y) }, [2]float64{-2, 20}, [2]float64{-2, 20}},
+		{"normalpdf(x)", func(x, y float64) float64 { return call("normalpdf", x) }, [2]float64{-4, 4}, [2]float64{0, 0}},
+		{"normalpdf(x, y, 2)", func(x, y float64) float64 { return call("normalpdf", x, y, 2) }, [2]float64{-4, 4}, [2]float64{-2, 2}},
+		{"normalpdf(1, 0, x)", func(x, y float64) float64 { return call("normalpdf", 1, 0, x) }, [2]float64{-2, 3}, [2]float64{0, 0}},
+		{"normalcdf(x, y, 2)", func(x, y float64) float64 { return call("normalcdf", x, y, 2) }, [2]float64{-4, 4}, [2]float64{-2, 2}},
+		{"normalcdf(x, y, 0, 1)", func(x, y float64) float64 { return call("normalcdf", x, y, 0, 1) }, [2]float64{-3, 3}, [2]float64{-3, 3}},
+		{"invnorm(x, y, 2)", func(x, y float64) float64 { return call("invnorm", x, y, 2) }, [2]float64{-0.2, 1.2}, [2]float64{-2, 2}},
+		{"binompdf(10, x, y)", func(x, y float64) float64 { return call("binompdf", 10, x, y) }, [2]float64{0, 1}, [2]float64{-2, 12}},
+		{"binompdf(y, 0.3, x)", func(x, y float64) float64 { return call("binompdf", y, 0.3, x) }, [2]float64{-2, 30}, [2]float64{-2, 30}},
+		{"binomcdf(y, x, 4)", func(x, y float64) float64 { return call("binomcdf", y, x, 4) }, [2]float64{-0.2, 1.2}, [2]float64{0, 20}},
+		{"binomcdf(20, 0.4, x)", func(x, y float64) float64 { return call("binomcdf", 20, 0.4, x) }, [2]float64{-2, 22}, [2]float64{0, 0}},
+		{"poissonpdf(x, y)", func(x, y float64) float64 { return call("poissonpdf", x, y) }, [2]float64{-1, 20}, [2]float64{-2, 30}},
+		{"poissonpdf(x, 200)", func(x, y float64) float64 { return call("poissonpdf", x, 200) }, [2]float64{150, 250}, [2]float64{0, 0}},
+		{"poissoncdf(x, y)", func(x, y float64) float64 { return call("poissoncdf", x, y) }, [2]float64{-1, 20}, [2]float64{-2, 30}},
+		{"tpdf(x, y)", func(x, y float64) float64 { return call("tpdf", x, y) }, [2]float64{-5, 5}, [2]float64{-1, 30}},
+		{"tcdf(x, y)", func(x, y float64) float64 { return call("tcdf", x, y) }, [2]float64{-5, 5}, [2]float64{-1, 30}},
+		{"invt(x, y)", func(x, y float64) float64 { return call("invt", x, y) }, [2]float64{-0.2, 1.2}, [2]float64{0.5, 30}},
+		{"chisqpdf(x, y)", func(x, y float64) float64 { return call("chisqpdf", x, y) }, [2]float64{-2, 20}, [2]float64{-1, 10}},
+		{"chisqcdf(x, y)", func(x, y float64) float64 { return call("chisqcdf", x, y) }, [2]float64{-2, 20}, [2]float64{-1, 10}},
+		{"invchisq(x, y)", func(x, y float64) float64 { return call("invchisq", x, y) }, [2]float64{-0.2, 1.2}, [2]float64{0.5, 10}},
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	// box draws a random box within the range, from a point up to the
+	// whole range
+	box := func(bounds [2]float64) (interval, [2]float64) {
+		w := (bounds[1] - bounds[0]) * math.Pow(10, -6*r.Float64())
+		if r.IntN(10) == 0 {
+			w = 0
+		}
+		lo := bounds[0] + r.Float64()*(bounds[1]-bounds[0]-w)
+		if r.IntN(4) == 0 {
+			// on an integer, where many functions change
+			lo = math.Round(lo) + 0 // not -0
+		}
+		return interval{lo, lo + w, true, true}, [2]float64{lo, lo + w}
+	}
+
+	for _, tt := range tests {
+		n, err := parseExpr(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		f, err := compileInterval(n, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+
+		for range 2000 {
+			x, bx := box(tt.x)
+			y, by := box(tt.y)
+			v := f(x, y)
+
+			for i := range 12 {
+				px, py := bx[0]+r.Float64()*(bx[1]-bx[0]), by[0]+r.Float64()*(by[1]-by[0])
+				if i < 4 {
+					px, py = bx[i%2], by[i/2]
+				}
+
+				want := tt.f(px, py)
+				if math.IsInf(want, 0) && !v.def {
+					// a pole, such as 1/0
+					continue
+				}
+				if math.IsNaN(want) {
+					if v.def {
+						t.Fatalf("%s over x = %v, y = %v is said to be defined, but it isn't at %v, %v", tt.expr, bx, by, px, py)
+					}
+					continue
+				}
+				if !(v.lo <= want && want <= v.hi) {
+					t.Fatalf("%s over x = %v, y = %v = [%v, %v], but it is %v at %v, %v", tt.expr, bx, by, v.lo, v.hi, want, px, py)
+				}
+			}
+		}
+	}
+}
+
+// TestIntervalRefused checks that the functions intervals can't enclose,
+// and calls with the wrong number of arguments, are errors rather than
+// drawn as undetermined everywhere.
+func TestIntervalRefused(t *testing.T) {
+	for _, s := range []string{"rndnormal(0, 1)", "p1(2, 2, 1, 1, x)", "sumseries(1, 1, 2)", "normalpdf(x, 1)", "sqrt(x, y)", "nope(x)"} {
+		n, err := parseExpr(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if _, err := compileInterval(n, nil); err == nil {
+			t.Errorf("%s compiles with intervals", s)
+		}
+	}
+}
+
+// TestIntervalPlotDecides checks that curves of the statistics functions
+// are drawn, rather than left undetermined over the whole view: only the
+// poles and the curves along the edges of pixels may be.
+func TestIntervalPlotDecides(t *testing.T) {
+	defer func(s, x, y float64) { scale, centerX, centerY = s, x, y }(scale, centerX, centerY)
+	scale, centerX, centerY = float64(graph.Rect.Dx())/12, 0, 0
+
+	c := color.RGBA{0xff, 0, 0, 0xff}
+	for _, s := range []string{"y = normalpdf(x)", "y = beta(x, 2)", "y = tcdf(x, 3)", "y = gamma(x)", "y = chisqpdf(x, 3)", "y = var(x, 1, 2)"} {
+		p, err := parseIntervalPlot(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+
+		clear(graph.Pix)
+		p.draw(c)
+
+		var on, unknown int
+		b := graph.Bounds()
+		for py := b.Min.Y; py < b.Max.Y; py++ {
+			for px := b.Min.X; px < b.Max.X; px++ {
+				switch graph.At(px, py) {
+				case color.RGBA64Model.Convert(c):
+					on++
+				case color.RGBA64Model.Convert(fade(c)):
+					unknown++
+				}
+			}
+		}
+		if on == 0 || unknown > b.Dx()*b.Dy()/50 {
+			t.Errorf("%s draws %d pixels and leaves %d undetermined", s, on, unknown)
+		}
+	}
+	clear(graph.Pix)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"math/cmplx"
+	"slices"
+)
+
+var errSingular = errors.New("matrix is singular")
+
+// solve solves a·x = b by Gaussian elimination with partial pivoting.
+// a and b are left untouched.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append(make([]float64, 0, n+1), a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-300 {
+			return nil, errSingular
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+
+	return x, nil
+}
+
+// inverse returns the inverse of the square matrix a.
+func inverse(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	inv := make([][]float64, n)
+	for i := range inv {
+		inv[i] = make([]float64, n)
+	}
+
+	e := make([]float64, n)
+	for col := 0; col < n; col++ {
+		clear(e)
+		e[col] = 1
+		x, err := solve(a, e)
+		if err != nil {
+			return nil, err
+		}
+		for row := range x {
+			inv[row][col] = x[row]
+		}
+	}
+
+	return inv, nil
+}
+
+// normalEquations returns JᵀJ and Jᵀr for the jacobian j (one row per
+// observation) and residuals r.
+func normalEquations(j [][]float64, r []float64) ([][]float64, []float64) {
+	p := len(j[0])
+	jtj := make([][]float64, p)
+	jtr := make([]float64, p)
+	for a := 0; a < p; a++ {
+		jtj[a] = make([]float64, p)
+		for i := range j {
+			jtr[a] += j[i][a] * r[i]
+			for b := 0; b < p; b++ {
+				jtj[a][b] += j[i][a] * j[i][b]
+			}
+		}
+	}
+
+	return jtj, jtr
+}
+
+// determinant returns the determinant of the square matrix a by Gaussian
+// elimination with partial pivoting.
+func determinant(a [][]float64) float64 {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append([]float64(nil), a[i]...)
+	}
+
+	det := 1.0
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if m[pivot][col] == 0 {
+			return 0
+		}
+		if pivot != col {
+			m[col], m[pivot] = m[pivot], m[col]
+			det = -det
+		}
+		det *= m[col][col]
+
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			for k := col; k < n; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+
+	return det
+}
+
+// characteristic returns the coefficients of det(λI - a), from λⁿ down to
+// the constant, by the Faddeev-LeVerrier algorithm.
+func characteristic(a [][]float64) []float64 {
+	n := len(a)
+	c := make([]float64, n+1)
+	c[0] = 1
+
+	// m is Mₖ, starting from M₀ = 0
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+	}
+	for k := 1; k <= n; k++ {
+		// Mₖ = a·Mₖ₋₁ + cₖ₋₁·I
+		next := make([][]float64, n)
+		for i := range next {
+			next[i] = make([]float64, n)
+			for j := range next[i] {
+				for l := 0; l < n; l++ {
+					next[i][j] += a[i][l] * m[l][j]
+				}
+			}
+			next[i][i] += c[k-1]
+		}
+		m = next
+
+		// cₖ = -tr(a·Mₖ)/k
+		var tr float64
+		for i := 0; i < n; i++ {
+			for l := 0; l < n; l++ {
+				tr += a[i][l] * m[l][i]
+			}
+		}
+		c[k] = -tr / float64(k)
+	}
+
+	return c
+}
+
+// eigen returns the eigenvalues of the square matrix a, and a unit
+// eigenvector for each real one (nil for the complex ones).
+func eigen(a [][]float64) ([]complex128, [][]float64) {
+	n := len(a)
+	values := polynomialRoots(characteristic(a))
+	slices.SortFunc(values, func(a, b complex128) int {
+		return cmp.Or(cmp.Compare(real(a), real(b)), cmp.Compare(imag(a), imag(b)))
+	})
+	vectors := make([][]float64, n)
+
+	for i, v := range values {
+		if math.Abs(imag(v)) > 1e-6*(1+math.Abs(real(v))) {
+			continue
+		}
+		values[i] = complex(real(v), 0)
+
+		// inverse iteration, slightly off the eigenvalue so that a-λI can
+		// be solved, starting from a basis vector so that repeated
+		// eigenvalues get different eigenvectors where they can
+		shifted := make([][]float64, n)
+		for r := range a {
+			shifted[r] = append([]float64(nil), a[r]...)
+			shifted[r][r] -= real(v) + 1e-10*(1+math.Abs(real(v)))
+		}
+		x := make([]float64, n)
+		x[i] = 1
+		for range 3 {
+			y, err := solve(shifted, x)
+			if err != nil {
+				break
+			}
+			x = normalize(y)
+		}
+		vectors[i] = x
+	}
+
+	return values, vectors
+}
+
+func normalize(x []float64) []float64 {
+	var norm float64
+	for _, v := range x {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	y := make([]float64, len(x))
+	for i, v := range x {
+		y[i] = v / norm
+	}
+
+	return y
+}
+
+// polynomialRoots returns the complex roots of the polynomial with the
+// coefficients c, from the leading one down, by the Durand-Kerner method.
+func polynomialRoots(c []float64) []complex128 {
+	n := len(c) - 1
+	roots := make([]complex128, n)
+	for i := range roots {
+		roots[i] = cmplx.Pow(0.4+0.9i, complex(float64(i), 0))
+	}
+
+	p := func(z complex128) complex128 {
+		v := complex(c[0], 0)
+		for _, k := range c[1:] {
+			v = v*z + complex(k, 0)
+		}
+		return v / complex(c[0], 0)
+	}
+
+	for range 500 {
+		var change float64
+		for i := range roots {
+			d := complex(1, 0)
+			for j := range roots {
+				if i != j {
+					d *= roots[i] - roots[j]
+				}
+			}
+			if d == 0 {
+				// roots that met, repeated ones, are as close as they get
+				continue
+			}
+			delta := p(roots[i]) / d
+			roots[i] -= delta
+			change = math.Max(change, cmplx.Abs(delta))
+		}
+		if change < 1e-14 {
+			break
+		}
+	}
+
+	return roots
+}
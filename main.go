@@ -6,14 +6,13 @@ import (
 	"image/color"
 	"image/jpeg"
 	"image/png"
-	"math"
+	"maps"
+	"math/rand/v2"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
-	"unsafe"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -47,14 +46,15 @@ func newWhiteBackground(w, h int) *image.Gray16 {
 }
 
 func main() {
-	a := app.New()
+	a := app.NewWithID("io.github.oqapps.qraph")
 	w := a.NewWindow("Qraph")
 
 	w.SetContent(
 		container.NewBorder(
 			container.NewCenter(widget.NewRichTextFromMarkdown("# Qraph")), nil, nil, nil,
 			container.NewAppTabs(
-				container.NewTabItem("Equations", equationsPage()),
+				container.NewTabItem("Equations", equationsPage(w)),
+				container.NewTabItem("3D", surfacePage()),
 				container.NewTabItem("Noise", perlinPage(w)),
 				container.NewTabItem("QR-Code", qrPage()),
 			),
@@ -63,9 +63,25 @@ func main() {
 	w.ShowAndRun()
 }
 
-func equationsPage() fyne.CanvasObject {
-	img := canvas.NewImageFromImage(graph)
-	img.ScaleMode = canvas.ImageScalePixels
+func equationsPage(w fyne.Window) fyne.CanvasObject {
+	var render func()
+
+	scaleInput := widget.NewEntry()
+
+	// clicking starts a trajectory of the last vector field
+	view := newGraphView(func(x, y float64) {
+		ids := plotIDs()
+		for i := len(ids) - 1; i >= 0; i-- {
+			if v := graphs[ids[i]].vectors; v != nil {
+				v.starts = append(v.starts, [2]float64{x, y})
+				render()
+				return
+			}
+		}
+	}, func() {
+		scaleInput.SetText(strconv.FormatFloat(scale, 'g', 6, 64))
+		render()
+	})
 
 	precisionInput := widget.NewEntry()
 	precisionInput.OnChanged = func(s string) {
@@ -77,6 +93,16 @@ func equationsPage() fyne.CanvasObject {
 	}
 	precisionInput.SetText(strconv.FormatFloat(precision, 'f', 2, 64))
 
+	prefs := fyne.CurrentApp().Preferences()
+	// the seed is kept as a string, as preferences store numbers as float64,
+	// which can't hold every 64 bit seed
+	if v, err := strconv.ParseUint(prefs.String("seed"), 10, 64); err == nil {
+		sceneSeed = v
+	}
+
+	seedInput := widget.NewEntry()
+	seedInput.SetText(strconv.FormatUint(sceneSeed, 10))
+
 	renderingText := widget.NewLabel("Rendering...")
 	renderingText.Hide()
 
@@ -84,343 +110,476 @@ func equationsPage() fyne.CanvasObject {
 	addGraph(constantY(0), color.White)
 
 	eqList := container.NewAdaptiveGrid(4)
+	entries := make(map[int]*widget.Entry)
 
-	return container.NewBorder(container.NewVBox(container.NewHBox(widget.NewLabel("Precision"), precisionInput, widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
-		color := colorrand()
+	render = func() {
+		renderingText.Show()
+		reset()
+		view.Refresh()
+		renderingText.Hide()
+	}
 
-		entry := widget.NewEntry()
+	setSeed := func(s uint64) {
+		sceneSeed = s
+		prefs.SetString("seed", strconv.FormatUint(s, 10))
+		seedInput.SetText(strconv.FormatUint(s, 10))
+		render()
+	}
 
-		entry.OnSubmitted = func(s string) {
-			g, err := parseMultiequationGraph(s)
-			if err != nil {
-				return
+	seedInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return
+		}
+		setSeed(i)
+	}
+
+	rerollButton := widget.NewButtonWithIcon("Reroll", theme.MediaReplayIcon(), func() {
+		setSeed(rand.Uint64())
+	})
+
+	scaleInput.SetText(strconv.FormatFloat(scale, 'f', 2, 64))
+	scaleInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil || i <= 0 {
+			return
+		}
+		scale = i
+		render()
+	}
+
+	bitsInput := widget.NewEntry()
+	bitsInput.SetText(strconv.FormatUint(uint64(bigPrecision), 10))
+	bitsInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || i < 64 {
+			return
+		}
+		bigPrecision = uint(i)
+		for _, id := range slices.Sorted(maps.Keys(entries)) {
+			if e := entries[id]; e.Text != "" {
+				e.OnSubmitted(e.Text)
 			}
+		}
+	}
 
-			graphs[color] = []Graph{g}
-			renderingText.Show()
-			reset()
-			img.Refresh()
-			renderingText.Hide()
+	complexCheck := widget.NewCheck("Complex", func(b bool) {
+		complexMode = b
+		for _, id := range slices.Sorted(maps.Keys(entries)) {
+			if e := entries[id]; e.Text != "" {
+				e.OnSubmitted(e.Text)
+			}
 		}
+	})
+
+	unitsCheck := widget.NewCheck("Units", func(b bool) {
+		unitsMode = b
+		for _, id := range slices.Sorted(maps.Keys(entries)) {
+			if e := entries[id]; e.Text != "" {
+				e.OnSubmitted(e.Text)
+			}
+		}
+	})
 
-		circle := canvas.NewRectangle(color)
-		circle.CornerRadius = 17
-		circle.SetMinSize(fyne.NewSquareSize(entry.MinSize().Height))
+	intervalsCheck := widget.NewCheck("Intervals", func(b bool) {
+		intervalMode = b
+		for _, id := range slices.Sorted(maps.Keys(entries)) {
+			if e := entries[id]; e.Text != "" {
+				e.OnSubmitted(e.Text)
+			}
+		}
+	})
+
+	addRow := func(id int, content fyne.CanvasObject) {
+		var sw *swatch
+		sw = newSwatch(graphs[id].color, widget.NewEntry().MinSize().Height, func() {
+			picker := dialog.NewColorPicker("Color", "Equation color", func(c color.Color) {
+				graphs[id].color = c
+				sw.SetColor(c)
+				render()
+			}, w)
+			picker.Advanced = true
+			picker.SetColor(graphs[id].color)
+			picker.Show()
+		})
+
+		var row *fyne.Container
 
 		deleteButton := &widget.Button{
 			Icon:       theme.ContentRemoveIcon(),
 			Importance: widget.DangerImportance,
 			OnTapped: func() {
-				i := colorIndexOf(eqList, color)
+				i := slices.Index(eqList.Objects, fyne.CanvasObject(row))
 				eqList.Objects = slices.Delete(eqList.Objects, i, i+1)
 				eqList.Refresh()
 
-				delete(graphs, color)
-				reset()
-				img.Refresh()
+				if d := graphs[id].points; d != nil {
+					delete(datasets, d.name)
+				}
+				delete(graphs, id)
+				delete(entries, id)
+				render()
 			},
 		}
 
-		eqList.Add(container.NewBorder(nil, nil, circle, deleteButton, entry))
-	})), eqList), container.NewHBox(layout.NewSpacer(), renderingText), nil, nil, img)
-}
+		row = container.NewBorder(nil, nil, sw, deleteButton, content)
+		eqList.Add(row)
+	}
 
-func colorIndexOf(cont *fyne.Container, c color.Color) int {
-	return slices.IndexFunc(cont.Objects, func(w fyne.CanvasObject) bool {
-		return w.(*fyne.Container).Objects[1].(*canvas.Rectangle).FillColor == c
-	})
-}
+	addButton := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		id := newPlotID()
+		graphs[id] = &plot{color: nextColor()}
 
-func perlinPage(w fyne.Window) fyne.CanvasObject {
-	whiteBackground := canvas.NewImageFromImage(newWhiteBackground(1200, 1200))
-	whiteBackground.ScaleMode = canvas.ImageScaleFastest
-	whiteBackground.FillMode = canvas.ImageFillContain
+		entry := widget.NewEntry()
 
-	var graph = image.NewNRGBA64(image.Rect(0, 0, 1200, 1200))
+		fitLabel := widget.NewLabel("")
+		fitLabel.Wrapping = fyne.TextWrapWord
+		var current *fit
+		fitDetails := widget.NewButtonWithIcon("", theme.InfoIcon(), func() {
+			residuals := canvas.NewImageFromImage(current.residualPlot(300, 150))
+			residuals.SetMinSize(fyne.NewSize(300, 150))
+
+			dialog.ShowCustom("Fit", "Close", container.NewVBox(
+				widget.NewLabel(current.details()),
+				widget.NewLabel("Residuals"),
+				residuals,
+			), w)
+		})
+		fitRow := container.NewBorder(nil, nil, nil, fitDetails, fitLabel)
+
+		contoursCheck := widget.NewCheck("Modulus contours", nil)
+
+		colormapSelect := widget.NewSelect(colormapNames, nil)
+		colormapSelect.SetSelected("Viridis")
+		levelsInput := widget.NewEntry()
+		levelsInput.SetText("8")
+		labelsCheck := widget.NewCheck("Labels", nil)
+		labelsCheck.SetChecked(true)
+		heatmapOptions := container.NewHBox(colormapSelect, widget.NewLabel("Contours"), levelsInput, labelsCheck)
+
+		arrowsSelect := widget.NewSelect([]string{"Arrows", "Slopes"}, nil)
+		arrowsSelect.SetSelected("Arrows")
+		methodSelect := widget.NewSelect([]string{"RK4", "RK45"}, nil)
+		methodSelect.SetSelected("RK45")
+		clearTrajectories := widget.NewButtonWithIcon("Trajectories", theme.ContentClearIcon(), nil)
+		vectorOptions := container.NewHBox(arrowsSelect, methodSelect, clearTrajectories)
+
+		matrixLabel := widget.NewLabel("")
+		matrixLabel.Wrapping = fyne.TextWrapWord
+		transformSlider := widget.NewSlider(0, 1)
+		transformSlider.Step = 0.01
+		playButton := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), nil)
+		transformRow := container.NewBorder(nil, nil, playButton, nil, transformSlider)
+		matrixOptions := container.NewVBox(matrixLabel, transformRow)
+
+		errorLabel := widget.NewLabel("")
+		errorLabel.Wrapping = fyne.TextWrapWord
+
+		options := container.NewVBox(errorLabel, fitRow, contoursCheck, heatmapOptions, vectorOptions, matrixOptions)
+		showOption := func(o fyne.CanvasObject) {
+			for _, obj := range options.Objects {
+				obj.Hide()
+			}
+			if o != nil {
+				o.Show()
+			}
+		}
+		showOption(nil)
 
-	var a, b = 2.0, 2.0
-	var n int32 = 1
-	var seed int64 = 123456
-	var colorMode = "NRGBA64"
-	var individualRefresh = false
-	var renderProgress = true
-	var useWhiteBackground = true
+		entry.OnSubmitted = func(s string) {
+			p := graphs[id]
+			lhs, _, _ := strings.Cut(s, "=")
 
-	var xDivide = 15.0
-	var zDivide = 15.0
-	var intensify = 1.0
+			switch {
+			case unitsMode:
+				g, axes, err := parseUnitGraph(s)
+				if err != nil {
+					errorLabel.SetText(err.Error())
+					showOption(errorLabel)
+					return
+				}
 
-	var alphaInput = widget.NewEntry()
-	var betaInput = widget.NewEntry()
-	var iterationsInput = widget.NewEntry()
-	var seedInput = widget.NewEntry()
+				showOption(nil)
+				p.clear()
+				p.graphs, p.axes = []Graph{g}, axes
+			case strings.TrimSpace(lhs) == "w":
+				d, err := parseDomainColoring(s)
+				if err != nil {
+					return
+				}
 
-	alphaInput.SetText(strconv.FormatFloat(a, 'f', 2, 64))
-	betaInput.SetText(strconv.FormatFloat(b, 'f', 2, 64))
-	iterationsInput.SetText(strconv.FormatInt(int64(n), 10))
-	seedInput.SetText(strconv.FormatInt(int64(seed), 10))
+				d.contours = contoursCheck.Checked
+				contoursCheck.OnChanged = func(b bool) {
+					d.contours = b
+					render()
+				}
+				showOption(contoursCheck)
+				p.clear()
+				p.domain = d
+			case strings.TrimSpace(lhs) == "z":
+				h, err := parseHeatmap(s)
+				if err != nil {
+					return
+				}
 
-	alphaInput.OnChanged = func(s string) {
-		i, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			return
-		}
-		a = i
-	}
-	betaInput.OnChanged = func(s string) {
-		i, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			return
-		}
-		b = i
-	}
-	iterationsInput.OnChanged = func(s string) {
-		i, err := strconv.ParseInt(s, 10, 32)
-		if err != nil {
-			return
-		}
-		n = int32(i)
-	}
-	seedInput.OnChanged = func(s string) {
-		i, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return
-		}
-		seed = i
-	}
+				h.colormap = colormaps[colormapSelect.Selected]
+				h.labels = labelsCheck.Checked
+				h.levels, h.contourCount, _ = parseLevels(levelsInput.Text)
 
-	img := canvas.NewImageFromImage(graph)
-	img.ScaleMode = canvas.ImageScaleFastest
-	img.FillMode = canvas.ImageFillContain
+				colormapSelect.OnChanged = func(s string) {
+					h.colormap = colormaps[s]
+					render()
+				}
+				labelsCheck.OnChanged = func(b bool) {
+					h.labels = b
+					render()
+				}
+				levelsInput.OnSubmitted = func(s string) {
+					levels, count, err := parseLevels(s)
+					if err != nil {
+						return
+					}
+					h.levels, h.contourCount = levels, count
+					render()
+				}
+				showOption(heatmapOptions)
+				p.clear()
+				p.field = h
+			case isVectorField(s):
+				v, err := parseVectorField(s)
+				if err != nil {
+					return
+				}
 
-	var pixelPerSecond = widget.NewLabel("N/A pps")
-	var rendering = widget.NewLabel("Rendering... N/A%")
-
-	var codeBlock = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
-
-	var rtc int32
-
-	var resetButton = widget.NewButton("Render", func() {
-		t := time.Now()
-		p := perlin.NewPerlin(a, b, n, seed)
-		min := graph.Rect.Min
-		max := graph.Rect.Max
-
-		totalPixels := max.X * max.Y
-
-		codeBlock.SetText(genCode(a, b, n, seed, xDivide, zDivide, intensify, max.X, max.Y))
-
-		var i int
-		for x := min.X; x < max.X; x++ {
-			for y := min.Y; y < max.Y; y++ {
-				l := p.Noise2D(float64(x)/xDivide, float64(y)/zDivide) * intensify
-
-				switch colorMode {
-				case "CMYK":
-					r := uint64(l*1000) + (math.Float64bits(l)>>52)*1000
-					z := uint32(r) + uint32(r>>32)
-
-					graph.Set(x, y, *(*color.CMYK)(unsafe.Pointer(&z)))
-				case "Gray":
-					graph.Set(x, y, color.Gray{Y: uint8(l * 255)})
-				case "Gray-16":
-					graph.Set(x, y, color.Gray16{Y: uint16(l * 65535)})
-				case "NRGBA":
-					graph.Set(x, y, color.NRGBA{A: uint8(l * 255)})
-				case "NRGBA64":
-					graph.Set(x, y, color.NRGBA64{A: uint16(l * 65535)})
-				case "NYCbCrA":
-					r := uint64(l*1000) + (math.Float64bits(l)>>52)*1000
-					z := uint32(r) + uint32(r>>32)
-					c := *(*color.NYCbCrA)(unsafe.Pointer(&z))
-					c.A = 255
-
-					graph.Set(x, y, c)
-				case "RGBA":
-					graph.Set(x, y, color.RGBA{A: uint8(l * 255)})
-				case "RGBA64":
-					graph.Set(x, y, color.RGBA64{A: uint16(l * 65535)})
-				case "YCbCr":
-					r := uint64(l*1000) + (math.Float64bits(l)>>52)*1000
-					z := uint32(r) + uint32(r>>32)
-
-					graph.Set(x, y, *(*color.YCbCr)(unsafe.Pointer(&z)))
+				if v.dx != nil {
+					v.slope = arrowsSelect.Selected == "Slopes"
+					arrowsSelect.Show()
+				} else {
+					arrowsSelect.Hide()
 				}
-				if individualRefresh {
-					img.Refresh()
+				v.adaptive = methodSelect.Selected == "RK45"
+				if old := p.vectors; old != nil {
+					v.starts = old.starts
 				}
-				i++
-				if renderProgress {
-					atomic.StoreInt32(&rtc, int32(i*100/totalPixels))
+
+				arrowsSelect.OnChanged = func(s string) {
+					v.slope = s == "Slopes"
+					render()
+				}
+				methodSelect.OnChanged = func(s string) {
+					v.adaptive = s == "RK45"
+					render()
+				}
+				clearTrajectories.OnTapped = func() {
+					v.starts = nil
+					render()
+				}
+				showOption(vectorOptions)
+				p.clear()
+				p.vectors = v
+			case isPiecewise(s) && !complexMode:
+				pw, err := parsePiecewise(s)
+				if err != nil {
+					return
 				}
-			}
-		}
 
-		if !individualRefresh {
-			img.Refresh()
-		}
+				showOption(nil)
+				p.clear()
+				p.pieces = pw
+			case isMatrixExpression(s):
+				m, err := parseMatrixPlot(s)
+				if err != nil {
+					matrixLabel.SetText(err.Error())
+					transformRow.Hide()
+					showOption(matrixOptions)
+					return
+				}
+
+				matrixLabel.SetText(m.summary())
+				if len(m.value) == 2 && len(m.value[0]) == 2 {
+					transformRow.Show()
+				} else {
+					transformRow.Hide()
+				}
+				transformSlider.OnChanged = nil
+				transformSlider.SetValue(1)
+				transformSlider.OnChanged = func(f float64) {
+					m.t = f
+					render()
+				}
+				playButton.OnTapped = func() {
+					fyne.NewAnimation(2*time.Second, func(f float32) {
+						transformSlider.SetValue(float64(f))
+					}).Start()
+				}
+				showOption(matrixOptions)
+				p.clear()
+				p.linear = m
+			case isRecurrence(s):
+				r, err := parseRecurrence(s)
+				if err != nil {
+					return
+				}
 
-		timeTaken := time.Since(t)
+				showOption(nil)
+				p.clear()
+				p.sequence = r
+			case strings.Contains(s, "~"):
+				f, err := parseFit(s)
+				if err != nil {
+					fitLabel.SetText(err.Error())
+					fitDetails.Hide()
+					showOption(fitRow)
+					return
+				}
 
-		pixelPerSecond.SetText(fmt.Sprintf("%d px/s (%ds)", int(float64(totalPixels)/math.Max(1, math.Ceil(timeTaken.Seconds()))), int(timeTaken.Seconds())))
-	})
+				current = f
+				f.onRun = func() {
+					fitLabel.SetText(f.summary())
+					fitDetails.Show()
+				}
+				f.run()
+				showOption(fitRow)
+				p.clear()
+				p.graphs, p.fit = []Graph{f.graph()}, f
+			case intervalMode && isRelation(s):
+				r, err := parseIntervalPlot(s)
+				if err != nil {
+					errorLabel.SetText(err.Error())
+					showOption(errorLabel)
+					return
+				}
 
-	go func() {
-		var oldRtc int32
-		for {
-			r := atomic.LoadInt32(&rtc)
-			if r == oldRtc {
-				continue
-			}
-			rendering.SetText(fmt.Sprintf("Rendering... %d%%", r))
-			oldRtc = r
-		}
-	}()
+				showOption(nil)
+				p.clear()
+				p.relation = r
+			default:
+				parse := parseMultiequationGraph
+				if complexMode {
+					parse = parseComplexGraph
+				}
 
-	var colorModeSelect = widget.NewSelect([]string{"CMYK", "Gray", "Gray-16", "NRGBA", "NRGBA64", "NYCbCrA", "RGBA", "RGBA64", "YCbCr"}, func(s string) {
-		colorMode = s
-	})
-	colorModeSelect.SetSelected(colorMode)
-	cM := container.NewBorder(nil, nil, widget.NewLabel("Color"), nil, colorModeSelect)
-	rM := widget.NewCheck("Real time reload", func(b bool) {
-		individualRefresh = b
-	})
-	pM := widget.NewCheck("Render progress", func(b bool) {
-		renderProgress = b
-	})
-	wB := widget.NewCheck("White background", func(b bool) {
-		useWhiteBackground = b
-		if !b {
-			whiteBackground.Hide()
-		} else {
-			if graph.Rect.Max != whiteBackground.Image.Bounds().Max {
-				whiteBackground.Image = newWhiteBackground(graph.Rect.Dx(), graph.Rect.Dy())
+				g, err := parse(s)
+				if err != nil {
+					return
+				}
+
+				showOption(nil)
+				p.clear()
+				p.graphs = []Graph{g}
+				if !complexMode {
+					// nil for expressions only float64 handles
+					p.precise, _ = parsePreciseGraph(s)
+				}
 			}
-			whiteBackground.Show()
+
+			render()
 		}
+		entries[id] = entry
+
+		addRow(id, container.NewBorder(nil, options, nil, nil, entry))
 	})
-	wB.SetChecked(useWhiteBackground)
-	pM.SetChecked(renderProgress)
 
-	var xDivideInput = widget.NewEntry()
-	xDivideInput.SetText(strconv.FormatFloat(xDivide, 'f', 2, 64))
-	xDivideInput.OnChanged = func(s string) {
-		i, err := strconv.ParseFloat(s, 64)
+	importButton := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		p, err := dialog2.File().Filter("Delimited text (.csv/.tsv/.txt)", "csv", "tsv", "txt").Load()
 		if err != nil {
 			return
 		}
-		xDivide = i
-	}
 
-	var zDivideInput = widget.NewEntry()
-	zDivideInput.SetText(strconv.FormatFloat(zDivide, 'f', 2, 64))
-	zDivideInput.OnChanged = func(s string) {
-		i, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			return
-		}
-		zDivide = i
-	}
+		showImportDialog(p, w, func(d *dataset) {
+			datasets[d.name] = d
 
-	var intensifyInput = widget.NewEntry()
-	intensifyInput.SetText(strconv.FormatFloat(intensify, 'f', 2, 64))
-	intensifyInput.OnChanged = func(s string) {
-		i, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			return
-		}
-		intensify = i
-	}
+			id := newPlotID()
+			graphs[id] = &plot{color: nextColor(), points: d}
 
-	topBottom := container.NewHBox(
-		cM,
-		container.NewGridWithColumns(3, widget.NewLabel("Focus"), xDivideInput, zDivideInput),
-		container.NewGridWithColumns(2, widget.NewLabel("Intensity"), intensifyInput),
-		wB,
-		rM,
-		pM,
-		widget.NewButton("Resize", func() {
-			var newWidth = graph.Rect.Dx()
-			var newHeight = graph.Rect.Dy()
-
-			var widthInput = widget.NewEntry()
-			widthInput.SetText(strconv.FormatInt(int64(newWidth), 10))
-			widthInput.OnChanged = func(s string) {
-				i, err := strconv.ParseInt(s, 10, 64)
-				if err != nil {
+			label := widget.NewLabel(fmt.Sprintf("%s (%d points)", d.name, len(d.values[d.xCol])))
+			reloadButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+				if err := d.reload(); err != nil {
+					dialog.ShowError(err, w)
 					return
 				}
-				newWidth = int(i)
-			}
 
-			var heightInput = widget.NewEntry()
-			heightInput.SetText(strconv.FormatInt(int64(newHeight), 10))
-			heightInput.OnChanged = func(s string) {
-				i, err := strconv.ParseInt(s, 10, 64)
-				if err != nil {
-					return
-				}
-				newHeight = int(i)
-			}
+				label.SetText(fmt.Sprintf("%s (%d points)", d.name, len(d.values[d.xCol])))
+				render()
+			})
 
-			width := container.NewGridWithColumns(2, widget.NewLabel("Width"), widthInput)
-			height := container.NewGridWithColumns(2, widget.NewLabel("Height"), heightInput)
+			addRow(id, container.NewBorder(nil, nil, nil, reloadButton, label))
+			render()
+		})
+	})
 
-			dialog := dialog.NewCustomWithoutButtons("Resize", container.NewCenter(container.NewVBox(width, height)), w)
-			dialog.SetButtons([]fyne.CanvasObject{
-				widget.NewButton("Resize", func() {
-					if useWhiteBackground {
-						whiteBackground.Image = newWhiteBackground(newWidth, newHeight)
-						whiteBackground.Refresh()
-					}
+	return container.NewBorder(container.NewVBox(container.NewHBox(widget.NewLabel("Precision"), precisionInput, widget.NewLabel("Scale"), scaleInput, widget.NewLabel("Bits"), bitsInput, widget.NewLabel("Seed"), seedInput, rerollButton, complexCheck, unitsCheck, intervalsCheck, addButton, importButton), eqList), container.NewHBox(layout.NewSpacer(), renderingText), nil, nil, view)
+}
 
-					graph = image.NewNRGBA64(image.Rect(0, 0, newWidth, newHeight))
-					img.Image = graph
-					img.Refresh()
-					dialog.Hide()
-				}),
-			})
+// showImportDialog lets the user pick how the delimited file at path is read
+// before it is imported as a dataset.
+func showImportDialog(path string, w fyne.Window, onImport func(*dataset)) {
+	var d *dataset
+
+	nameInput := widget.NewEntry()
+	xSelect := widget.NewSelect(nil, nil)
+	ySelect := widget.NewSelect(nil, nil)
+	headerCheck := widget.NewCheck("Header row", nil)
+	delimSelect := widget.NewSelect([]string{"Auto", "Comma", "Semicolon", "Tab", "Space"}, nil)
+	markersCheck := widget.NewCheck("Markers", nil)
+	linesCheck := widget.NewCheck("Connect points", nil)
+	errorLabel := widget.NewLabel("")
+	markersCheck.SetChecked(true)
+
+	load := func(detectHeaderRow bool) {
+		records, delim, err := readRecords(path, delimiters[delimSelect.Selected])
+		if err != nil {
+			errorLabel.SetText(err.Error())
+			return
+		}
+		errorLabel.SetText("")
 
-			dialog.Show()
-		}),
-		widget.NewButton("Export", func() {
-			p, err := dialog2.File().Filter("PNG (.png)", "png").Filter("JPEG (.jpg/.jpeg/.jfif)", ".jpg", ".jpeg", ".jfif").Save()
-			if err != nil {
-				return
-			}
-			extI := strings.LastIndex(p, ".")
-			var ext string
-			if extI != -1 {
-				ext = p[extI:]
-			}
+		if detectHeaderRow && len(records) != 0 {
+			headerCheck.Checked = detectHeader(records[0])
+			headerCheck.Refresh()
+		}
 
-			file, _ := os.Create(p)
+		d = newDataset(path, records, headerCheck.Checked)
+		d.delim = delim
+		if nameInput.Text == "" {
+			nameInput.SetText(d.name)
+		}
 
-			switch ext {
-			case "jpeg", "jpg", "jfif":
-				jpeg.Encode(file, graph, nil)
-			default:
-				png.Encode(file, graph)
-			}
+		xSelect.Options = d.columns
+		ySelect.Options = d.columns
+		xSelect.SetSelectedIndex(d.xCol)
+		ySelect.SetSelectedIndex(d.yCol)
+	}
 
-			file.Close()
-		}),
+	delimSelect.SetSelected("Auto")
+	load(true)
+	delimSelect.OnChanged = func(string) { load(true) }
+	headerCheck.OnChanged = func(bool) { load(false) }
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameInput),
+		widget.NewFormItem("Delimiter", delimSelect),
+		widget.NewFormItem("", headerCheck),
+		widget.NewFormItem("X", xSelect),
+		widget.NewFormItem("Y", ySelect),
+		widget.NewFormItem("", container.NewHBox(markersCheck, linesCheck)),
 	)
 
-	var top = container.NewBorder(nil, topBottom, nil, resetButton, container.NewGridWithColumns(4,
-		container.NewBorder(nil, nil, widget.NewLabel("Alpha"), nil, alphaInput),
-		container.NewBorder(nil, nil, widget.NewLabel("Beta"), nil, betaInput),
-		container.NewBorder(nil, nil, widget.NewLabel("Iterations"), nil, iterationsInput),
-		container.NewBorder(nil, nil, widget.NewLabel("Seed"), nil, seedInput),
-	))
+	dialog.ShowCustomConfirm("Import data", "Import", "Cancel", container.NewVBox(form, errorLabel), func(ok bool) {
+		if !ok || d == nil || len(d.columns) == 0 {
+			return
+		}
 
-	return container.NewBorder(top, container.NewHBox(container.NewBorder(nil, pixelPerSecond, nil, nil,
-		container.NewBorder(widget.NewLabel("Code: "), nil, nil, nil, codeBlock),
-	), layout.NewSpacer(), container.NewVBox(layout.NewSpacer(), rendering)), nil, nil, container.NewStack(whiteBackground, img))
-}
+		if name := identifier(nameInput.Text, d.name); datasets[name] == nil {
+			d.name = name
+		}
+		d.xCol = max(xSelect.SelectedIndex(), 0)
+		d.yCol = max(ySelect.SelectedIndex(), 0)
+		d.markers = markersCheck.Checked
+		d.lines = linesCheck.Checked
 
-func genCode(a, b float64, i int32, seed int64, xd, zd, is float64, w, h int) string {
-	return fmt.Sprintf("import \"github.com/aquilax/go-perlin\"\n\nvar p = perlin.NewPerlin(%f, %f, %d, %d)\nfor x := 0; x < %d; x++ {\n\tfor y := 0; y < %d; y++ {\n\t\tvar value = p.Perlin2D(x/%f, y/%f)*%f\n\t}\n}", a, b, i, seed, w, h, xd, zd, is)
+		onImport(d)
+	}, w)
 }
 
 func qrPage() fyne.CanvasObject {
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// mapOutputs are what the Noise tab shows: the colored noise, or maps
+// derived from it as a height field.
+var mapOutputs = []string{"Color", "Height", "Normal (OpenGL)", "Normal (DirectX)", "Hillshade", "Slope", "Ambient occlusion"}
+
+// mapSettings are the settings of the derived maps. scale is how high a
+// height of 1 is, in pixels, the sun is azimuth degrees clockwise from the
+// top of the image and altitude degrees above the horizon, and radius is
+// how far, in pixels, ambient occlusion looks for what hides the sky.
+type mapSettings struct {
+	scale             float64
+	azimuth, altitude float64
+	radius            float64
+}
+
+// slope is the height gradient at x, y in pixels per pixel, by central
+// differences, one-sided at the edges, with y growing down the image.
+func (f heightField) slope(x, y int, scale float64) (float64, float64) {
+	x0, x1 := max(0, x-1), min(f.width-1, x+1)
+	y0, y1 := max(0, y-1), min(f.height-1, y+1)
+
+	var dx, dy float64
+	if x1 > x0 {
+		dx = (f.at(x1, y) - f.at(x0, y)) / float64(x1-x0)
+	}
+	if y1 > y0 {
+		dy = (f.at(x, y1) - f.at(x, y0)) / float64(y1-y0)
+	}
+
+	return dx * scale, dy * scale
+}
+
+// normal is the unit normal at x, y, with y pointing up the image.
+func (f heightField) normal(x, y int, scale float64) (float64, float64, float64) {
+	dx, dy := f.slope(x, y, scale)
+	nx, ny, nz := -dx, dy, 1.0
+	l := math.Sqrt(nx*nx + ny*ny + nz*nz)
+
+	return nx / l, ny / l, nz / l
+}
+
+// normalMap is the tangent-space normal map, green pointing up the image
+// as OpenGL expects, or down it as DirectX does.
+func (f heightField) normalMap(scale float64, directX bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, f.width, f.height))
+	unit := func(v float64) uint8 {
+		return uint8((v+1)/2*255 + 0.5)
+	}
+	for y := range f.height {
+		for x := range f.width {
+			nx, ny, nz := f.normal(x, y, scale)
+			if directX {
+				ny = -ny
+			}
+			img.SetNRGBA(x, y, color.NRGBA{unit(nx), unit(ny), unit(nz), 0xff})
+		}
+	}
+
+	return img
+}
+
+// hillshade lights the field by a sun at azimuth and altitude, in
+// degrees, as Lambertian shading.
+func (f heightField) hillshade(scale, azimuth, altitude float64) *image.Gray {
+	az, alt := azimuth*math.Pi/180, altitude*math.Pi/180
+	lx, ly, lz := math.Sin(az)*math.Cos(alt), math.Cos(az)*math.Cos(alt), math.Sin(alt)
+
+	img := image.NewGray(image.Rect(0, 0, f.width, f.height))
+	for y := range f.height {
+		for x := range f.width {
+			nx, ny, nz := f.normal(x, y, scale)
+			img.SetGray(x, y, color.Gray{uint8(max(0, nx*lx+ny*ly+nz*lz)*255 + 0.5)})
+		}
+	}
+
+	return img
+}
+
+// slopeMap is the steepness of the field, from black for flat to white for
+// vertical.
+func (f heightField) slopeMap(scale float64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, f.width, f.height))
+	for y := range f.height {
+		for x := range f.width {
+			dx, dy := f.slope(x, y, scale)
+			a := math.Atan(math.Hypot(dx, dy)) / (math.Pi / 2)
+			img.SetGray(x, y, color.Gray{uint8(a*255 + 0.5)})
+		}
+	}
+
+	return img
+}
+
+// aoDirections and aoSteps are how many directions and samples along each
+// ambientOcclusion looks for the horizon in.
+const (
+	aoDirections = 8
+	aoSteps      = 4
+)
+
+// ambientOcclusion approximates how much of the sky each point sees, by
+// the highest horizon in a few directions within radius pixels, from black
+// for hidden to white for open.
+func (f heightField) ambientOcclusion(scale, radius float64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, f.width, f.height))
+
+	var dirs [aoDirections][2]float64
+	for i := range dirs {
+		a := 2 * math.Pi * float64(i) / aoDirections
+		dirs[i] = [2]float64{math.Cos(a), math.Sin(a)}
+	}
+
+	for y := range f.height {
+		for x := range f.width {
+			h := f.at(x, y) * scale
+			occlusion := 0.0
+			for _, d := range dirs {
+				horizon := 0.0
+				for s := 1; s <= aoSteps; s++ {
+					r := radius * float64(s) / aoSteps
+					sx, sy := x+int(math.Round(d[0]*r)), y+int(math.Round(d[1]*r))
+					if sx < 0 || sy < 0 || sx >= f.width || sy >= f.height {
+						break
+					}
+					// the sine of the angle above the horizontal
+					rise := f.at(sx, sy)*scale - h
+					horizon = max(horizon, rise/math.Hypot(rise, r))
+				}
+				occlusion += horizon
+			}
+			img.SetGray(x, y, color.Gray{uint8((1-occlusion/aoDirections)*255 + 0.5)})
+		}
+	}
+
+	return img
+}
+
+// derived is the map output is, nil for the colored noise.
+func (f heightField) derived(output string, s mapSettings) image.Image {
+	switch output {
+	case "Height":
+		return f.gray16()
+	case "Normal (OpenGL)":
+		return f.normalMap(s.scale, false)
+	case "Normal (DirectX)":
+		return f.normalMap(s.scale, true)
+	case "Hillshade":
+		return f.hillshade(s.scale, s.azimuth, s.altitude)
+	case "Slope":
+		return f.slopeMap(s.scale)
+	case "Ambient occlusion":
+		return f.ambientOcclusion(s.scale, s.radius)
+	}
+
+	return nil
+}
@@ -0,0 +1,636 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matrix is the value of a matrix expression, as its rows. Scalars are
+// 1×1 matrices and vectors are columns.
+type matrix [][]float64
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+
+	return m
+}
+
+// maxMatrixSize and maxMatrixPower bound the matrices identity makes and
+// the powers ^ takes, which would otherwise run out of memory or time.
+const (
+	maxMatrixSize  = 256
+	maxMatrixPower = 1 << 20
+)
+
+func identity(n int) matrix {
+	m := newMatrix(n, n)
+	for i := range m {
+		m[i][i] = 1
+	}
+
+	return m
+}
+
+func (m matrix) isScalar() bool {
+	return len(m) == 1 && len(m[0]) == 1
+}
+
+func (m matrix) isSquare() bool {
+	return len(m) == len(m[0])
+}
+
+func (m matrix) transpose() matrix {
+	t := newMatrix(len(m[0]), len(m))
+	for i := range m {
+		for j := range m[i] {
+			t[j][i] = m[i][j]
+		}
+	}
+
+	return t
+}
+
+func (m matrix) mul(b matrix) (matrix, error) {
+	if len(m[0]) != len(b) {
+		return nil, fmt.Errorf("can't multiply %d×%d by %d×%d", len(m), len(m[0]), len(b), len(b[0]))
+	}
+
+	p := newMatrix(len(m), len(b[0]))
+	for i := range p {
+		for j := range p[i] {
+			for k := range b {
+				p[i][j] += m[i][k] * b[k][j]
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// apply returns f of every element of m.
+func (m matrix) apply(f func(float64) float64) matrix {
+	r := newMatrix(len(m), len(m[0]))
+	for i := range m {
+		for j := range m[i] {
+			r[i][j] = f(m[i][j])
+		}
+	}
+
+	return r
+}
+
+// String formats m the way it is written in expressions.
+func (m matrix) String() string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', 4, 64) }
+
+	if m.isScalar() {
+		return format(m[0][0])
+	}
+	if len(m[0]) == 1 {
+		l := make([]string, len(m))
+		for i := range m {
+			l[i] = format(m[i][0])
+		}
+		return "[" + strings.Join(l, ", ") + "]"
+	}
+
+	rows := make([]string, len(m))
+	for i := range m {
+		l := make([]string, len(m[i]))
+		for j := range m[i] {
+			l[j] = format(m[i][j])
+		}
+		rows[i] = "[" + strings.Join(l, ", ") + "]"
+	}
+
+	return "[" + strings.Join(rows, ", ") + "]"
+}
+
+var scalarOperators = map[string]func(a, b float64) float64{
+	"+":  func(a, b float64) float64 { return a + b },
+	"-":  func(a, b float64) float64 { return a - b },
+	"*":  func(a, b float64) float64 { return a * b },
+	"/":  func(a, b float64) float64 { return a / b },
+	"%":  math.Mod,
+	"^":  math.Pow,
+	"<":  func(a, b float64) float64 { return boolValue(a < b) },
+	"<=": func(a, b float64) float64 { return boolValue(a <= b) },
+	">":  func(a, b float64) float64 { return boolValue(a > b) },
+	">=": func(a, b float64) float64 { return boolValue(a >= b) },
+	"==": func(a, b float64) float64 { return boolValue(a == b) },
+	"!=": func(a, b float64) float64 { return boolValue(a != b) },
+	"&&": func(a, b float64) float64 { return boolValue(a != 0 && b != 0) },
+	"||": func(a, b float64) float64 { return boolValue(a != 0 || b != 0) },
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+var matrixFunctions = map[string]func(args []matrix) (matrix, error){
+	"det": func(args []matrix) (matrix, error) {
+		a, err := squareArg(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return matrix{{determinant(a)}}, nil
+	},
+	"inv": func(args []matrix) (matrix, error) {
+		a, err := squareArg(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return inverse(a)
+	},
+	"transpose": func(args []matrix) (matrix, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected a matrix")
+		}
+
+		return args[0].transpose(), nil
+	},
+	"trace": func(args []matrix) (matrix, error) {
+		a, err := squareArg(args)
+		if err != nil {
+			return nil, err
+		}
+
+		var tr float64
+		for i := range a {
+			tr += a[i][i]
+		}
+
+		return matrix{{tr}}, nil
+	},
+	"identity": func(args []matrix) (matrix, error) {
+		if len(args) != 1 || !args[0].isScalar() {
+			return nil, fmt.Errorf("expected a size")
+		}
+		if n := args[0][0][0]; !(n >= 1 && n <= maxMatrixSize) {
+			return nil, fmt.Errorf("the size must be between 1 and %d", maxMatrixSize)
+		}
+
+		return identity(int(args[0][0][0])), nil
+	},
+	"dot": func(args []matrix) (matrix, error) {
+		if len(args) != 2 || len(args[0][0]) != 1 || len(args[1][0]) != 1 || len(args[0]) != len(args[1]) {
+			return nil, fmt.Errorf("expected two vectors of the same size")
+		}
+
+		return args[0].transpose().mul(args[1])
+	},
+	"cross": func(args []matrix) (matrix, error) {
+		if len(args) != 2 || len(args[0]) != 3 || len(args[1]) != 3 || len(args[0][0]) != 1 || len(args[1][0]) != 1 {
+			return nil, fmt.Errorf("expected two 3D vectors")
+		}
+		a, b := args[0], args[1]
+
+		return matrix{
+			{a[1][0]*b[2][0] - a[2][0]*b[1][0]},
+			{a[2][0]*b[0][0] - a[0][0]*b[2][0]},
+			{a[0][0]*b[1][0] - a[1][0]*b[0][0]},
+		}, nil
+	},
+	"norm": func(args []matrix) (matrix, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected a vector or a matrix")
+		}
+
+		var sum float64
+		for _, row := range args[0] {
+			for _, v := range row {
+				sum += v * v
+			}
+		}
+
+		return matrix{{math.Sqrt(sum)}}, nil
+	},
+	// eigvals are the eigenvalues in increasing order, NaN for the
+	// complex ones.
+	"eigvals": func(args []matrix) (matrix, error) {
+		a, err := squareArg(args)
+		if err != nil {
+			return nil, err
+		}
+
+		values, _ := eigen(a)
+		m := newMatrix(len(values), 1)
+		for i, v := range values {
+			m[i][0] = math.NaN()
+			if imag(v) == 0 {
+				m[i][0] = real(v)
+			}
+		}
+
+		return m, nil
+	},
+	// eigvecs are the unit eigenvectors as columns, in the order of
+	// eigvals.
+	"eigvecs": func(args []matrix) (matrix, error) {
+		a, err := squareArg(args)
+		if err != nil {
+			return nil, err
+		}
+
+		_, vectors := eigen(a)
+		m := newMatrix(len(a), len(a))
+		for j, v := range vectors {
+			for i := range m {
+				m[i][j] = math.NaN()
+				if v != nil {
+					m[i][j] = v[i]
+				}
+			}
+		}
+
+		return m, nil
+	},
+}
+
+func squareArg(args []matrix) (matrix, error) {
+	if len(args) != 1 || !args[0].isSquare() {
+		return nil, fmt.Errorf("expected a square matrix")
+	}
+
+	return args[0], nil
+}
+
+// evalMatrix evaluates an expression of matrices, vectors and scalars.
+// Functions of scalars are those of the other equations.
+func evalMatrix(n *node) (matrix, error) {
+	args := make([]matrix, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], err = evalMatrix(a); err != nil {
+			return nil, err
+		}
+	}
+
+	switch n.op {
+	case "num":
+		return matrix{{n.value}}, nil
+	case "var":
+		if c, ok := complexConstants[n.name]; ok && imag(c) == 0 {
+			return matrix{{real(c)}}, nil
+		}
+		if m, ok := namedMatrix(n.name); ok {
+			return m, nil
+		}
+
+		return nil, fmt.Errorf("unknown variable '%s'", n.name)
+	case "list":
+		return listMatrix(args)
+	case "neg":
+		return args[0].apply(func(v float64) float64 { return -v }), nil
+	case "!":
+		if !args[0].isScalar() {
+			return nil, fmt.Errorf("'!' is only defined on scalars")
+		}
+		return matrix{{boolValue(args[0][0][0] == 0)}}, nil
+	case "?":
+		if !args[0].isScalar() {
+			return nil, fmt.Errorf("the condition must be a scalar")
+		}
+		if args[0][0][0] != 0 {
+			return args[1], nil
+		}
+		return args[2], nil
+	case "call":
+		if f, ok := matrixFunctions[n.name]; ok {
+			return f(args)
+		}
+
+		f, ok := functions[n.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function '%s'", n.name)
+		}
+		scalars := make([]interface{}, len(args))
+		for i, a := range args {
+			if !a.isScalar() {
+				return nil, fmt.Errorf("%s only takes scalars", n.name)
+			}
+			scalars[i] = a[0][0]
+		}
+		v, err := f(scalars...)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s doesn't return a number", n.name)
+		}
+
+		return matrix{{r}}, nil
+	}
+
+	return binaryMatrix(n.op, args[0], args[1])
+}
+
+// listMatrix makes a vector of scalars, or a matrix of vectors as its
+// rows.
+func listMatrix(items []matrix) (matrix, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("empty vector")
+	}
+
+	if items[0].isScalar() {
+		m := newMatrix(len(items), 1)
+		for i, it := range items {
+			if !it.isScalar() {
+				return nil, fmt.Errorf("a vector is made of numbers")
+			}
+			m[i][0] = it[0][0]
+		}
+		return m, nil
+	}
+
+	m := make(matrix, len(items))
+	for i, it := range items {
+		if len(it[0]) != 1 || len(it) != len(items[0]) {
+			return nil, fmt.Errorf("the rows of a matrix are vectors of the same size")
+		}
+		m[i] = it.transpose()[0]
+	}
+
+	return m, nil
+}
+
+func binaryMatrix(op string, a, b matrix) (matrix, error) {
+	if a.isScalar() && b.isScalar() {
+		f, ok := scalarOperators[op]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator '%s'", op)
+		}
+		return matrix{{f(a[0][0], b[0][0])}}, nil
+	}
+
+	switch op {
+	case "+", "-":
+		if len(a) != len(b) || len(a[0]) != len(b[0]) {
+			return nil, fmt.Errorf("can't add %d×%d and %d×%d", len(a), len(a[0]), len(b), len(b[0]))
+		}
+		sign := 1.0
+		if op == "-" {
+			sign = -1
+		}
+		r := newMatrix(len(a), len(a[0]))
+		for i := range r {
+			for j := range r[i] {
+				r[i][j] = a[i][j] + sign*b[i][j]
+			}
+		}
+		return r, nil
+	case "*":
+		switch {
+		case a.isScalar():
+			return b.apply(func(v float64) float64 { return a[0][0] * v }), nil
+		case b.isScalar():
+			return a.apply(func(v float64) float64 { return v * b[0][0] }), nil
+		}
+		return a.mul(b)
+	case "/":
+		if !b.isScalar() {
+			return nil, fmt.Errorf("can only divide by a scalar, use inv")
+		}
+		return a.apply(func(v float64) float64 { return v / b[0][0] }), nil
+	case "^":
+		if !a.isSquare() || !b.isScalar() || b[0][0] != math.Trunc(b[0][0]) {
+			return nil, fmt.Errorf("only square matrices have integer powers")
+		}
+		if math.Abs(b[0][0]) > maxMatrixPower {
+			return nil, fmt.Errorf("the power must be at most %d", maxMatrixPower)
+		}
+		k := int(b[0][0])
+		if k < 0 {
+			inv, err := inverse(a)
+			if err != nil {
+				return nil, err
+			}
+			a, k = inv, -k
+		}
+		// by squaring
+		r := identity(len(a))
+		for ; k > 0; k >>= 1 {
+			if k&1 == 1 {
+				r, _ = r.mul(a)
+			}
+			a, _ = a.mul(a)
+		}
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is only defined on scalars", op)
+}
+
+// matrixPlot is an equation made of vectors and matrices. A 2D vector is
+// drawn as an arrow and a 2×2 matrix as the transform of the unit grid and
+// circle, with its eigenvectors.
+type matrixPlot struct {
+	value matrix
+
+	// name is what other rows call value by, if it is named.
+	name string
+
+	// t animates the transform from the identity at 0 to value at 1.
+	t float64
+
+	eigenvalues  []complex128
+	eigenvectors [][]float64
+}
+
+// listLiteral matches the start of a vector or matrix literal: a bracket
+// opening a number, a sign, a parenthesis or another bracket, or holding a
+// comma. govaluate's escaped variables, such as [table.column], don't.
+var listLiteral = regexp.MustCompile(`\[\s*([\d.+\-(\[]|[^\]]*,)`)
+
+// identifiers matches the names in an expression.
+var identifiers = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+// isMatrixExpression reports whether str has vector or matrix literals, or
+// uses a named matrix, which the other equations don't.
+func isMatrixExpression(str string) bool {
+	if listLiteral.MatchString(str) {
+		return true
+	}
+
+	for _, name := range identifiers.FindAllString(str, -1) {
+		if _, ok := namedMatrix(name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namedMatrix returns the value of the row defining the matrix name.
+func namedMatrix(name string) (matrix, bool) {
+	for _, p := range graphs {
+		if p.linear != nil && p.linear.name == name {
+			return p.linear.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseMatrixPlot parses an expression of matrices, optionally named as in
+// A = [[1, 2], [3, 4]] so that the rows parsed after can use A.
+func parseMatrixPlot(str string) (*matrixPlot, error) {
+	var name string
+	if lhs, rhs, ok := strings.Cut(str, "="); ok && identifierPattern.MatchString(strings.TrimSpace(lhs)) && !strings.HasPrefix(rhs, "=") {
+		name, str = strings.TrimSpace(lhs), rhs
+	}
+
+	n, err := parseExpr(str)
+	if err != nil {
+		return nil, err
+	}
+	m, err := evalMatrix(n)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &matrixPlot{value: m, name: name, t: 1}
+	if m.isSquare() {
+		p.eigenvalues, p.eigenvectors = eigen(m)
+	}
+
+	return p, nil
+}
+
+func (p *matrixPlot) summary() string {
+	s := p.value.String()
+	if p.value.isScalar() || !p.value.isSquare() {
+		return s
+	}
+
+	values := make([]string, len(p.eigenvalues))
+	for i, v := range p.eigenvalues {
+		values[i] = strconv.FormatFloat(real(v), 'g', 4, 64)
+		if imag(v) != 0 {
+			values[i] = strconv.FormatComplex(v, 'g', 4, 128)
+		}
+	}
+
+	return fmt.Sprintf("%s\ndet = %.4g, λ = %s", s, determinant(p.value), strings.Join(values, ", "))
+}
+
+// current returns the transform at p.t, between the identity and p.value.
+func (p *matrixPlot) current() matrix {
+	m := identity(2)
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] += p.t * (p.value[i][j] - m[i][j])
+		}
+	}
+
+	return m
+}
+
+func (p *matrixPlot) draw(c color.Color) {
+	switch {
+	case len(p.value) == 2 && len(p.value[0]) == 1:
+		ox, oy := toPixel(0, 0)
+		px, py := toPixel(p.value[0][0], p.value[1][0])
+		drawArrow(ox, oy, px, py, c)
+	case len(p.value) == 2 && len(p.value[0]) == 2:
+		p.drawTransform(c)
+	}
+}
+
+func (p *matrixPlot) drawTransform(c color.Color) {
+	a := p.current()
+	at := func(x, y float64) (float64, float64) {
+		return toPixel(a[0][0]*x+a[0][1]*y, a[1][0]*x+a[1][1]*y)
+	}
+
+	// the grid covers the view once transformed, so it extends as far as
+	// the inverse transform sends the corners of the view
+	left, top := fromPixel(0, 0)
+	right, bottom := fromPixel(float64(graph.Rect.Dx()), float64(graph.Rect.Dy()))
+	extent := math.Max(math.Max(math.Abs(left), math.Abs(right)), math.Max(math.Abs(top), math.Abs(bottom)))
+	if inv, err := inverse(a); err == nil {
+		norm := math.Max(math.Abs(inv[0][0])+math.Abs(inv[0][1]), math.Abs(inv[1][0])+math.Abs(inv[1][1]))
+		extent *= math.Min(norm, 20)
+	}
+	step := niceStep(30 / scale)
+	for extent/step > 200 {
+		step *= 2
+	}
+	extent = math.Ceil(extent/step) * step
+
+	faded := fade(c)
+	for k := -extent; k <= extent; k += step {
+		lc := faded
+		if math.Abs(k) < step/2 {
+			lc = c
+		}
+		x0, y0 := at(k, -extent)
+		x1, y1 := at(k, extent)
+		drawLine(x0, y0, x1, y1, lc)
+		x0, y0 = at(-extent, k)
+		x1, y1 = at(extent, k)
+		drawLine(x0, y0, x1, y1, lc)
+	}
+
+	const segments = 256
+	lx, ly := at(1, 0)
+	for i := 1; i <= segments; i++ {
+		θ := 2 * math.Pi * float64(i) / segments
+		x, y := at(math.Cos(θ), math.Sin(θ))
+		drawLine(lx, ly, x, y, c)
+		lx, ly = x, y
+	}
+
+	ox, oy := toPixel(0, 0)
+	ix, iy := at(1, 0)
+	jx, jy := at(0, 1)
+	drawArrow(ox, oy, ix, iy, c)
+	drawArrow(ox, oy, jx, jy, c)
+
+	// eigenvectors keep their direction all along the animation, only
+	// their length changes
+	for _, v := range p.eigenvectors {
+		if v == nil {
+			continue
+		}
+		x0, y0 := toPixel(-v[0]*extent, -v[1]*extent)
+		x1, y1 := toPixel(v[0]*extent, v[1]*extent)
+		drawLine(x0, y0, x1, y1, fade(color.White))
+
+		ex, ey := at(v[0], v[1])
+		drawArrow(ox, oy, ex, ey, color.White)
+	}
+}
+
+// niceStep returns the smallest of 1, 2 and 5 times a power of ten that is
+// at least least.
+func niceStep(least float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(least)))
+	for _, f := range []float64{1, 2, 5, 10} {
+		if f*p >= least {
+			return f * p
+		}
+	}
+
+	return 10 * p
+}
+
+// fade returns c at a third of its opacity.
+func fade(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{uint16(r / 3), uint16(g / 3), uint16(b / 3), uint16(a / 3)}
+}
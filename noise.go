@@ -0,0 +1,398 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/aquilax/go-perlin"
+)
+
+// noiseGenerator is a 2D noise field for the Noise tab, its values lying
+// roughly within [-1, 1].
+type noiseGenerator interface {
+	Noise2D(x, y float64) float64
+}
+
+// noiseParam is a setting of a noise algorithm, shown as an entry, or as a
+// select if it has options, whose index is then its value.
+type noiseParam struct {
+	name    string
+	value   float64
+	options []string
+}
+
+// noiseAlgorithm is an entry of the algorithm select of the Noise tab.
+type noiseAlgorithm struct {
+	name   string
+	params []noiseParam
+	new    func(seed int64, params []float64) noiseGenerator
+}
+
+var noiseAlgorithms = []noiseAlgorithm{
+	{
+		name: "Perlin",
+		params: []noiseParam{
+			{name: "Alpha", value: 2},
+			{name: "Beta", value: 2},
+			{name: "Iterations", value: 1},
+		},
+		new: func(seed int64, p []float64) noiseGenerator {
+			return perlin.NewPerlin(p[0], p[1], int32(p[2]), seed)
+		},
+	},
+	{
+		name: "OpenSimplex2",
+		new: func(seed int64, p []float64) noiseGenerator {
+			return openSimplex2{uint64(seed)}
+		},
+	},
+	{
+		name: "Value",
+		params: []noiseParam{
+			{name: "Interpolation", value: 2, options: []string{"Linear", "Cubic", "Quintic"}},
+		},
+		new: func(seed int64, p []float64) noiseGenerator {
+			return valueNoise{seed: uint64(seed), interpolation: int(p[0])}
+		},
+	},
+	{
+		name: "Worley",
+		params: []noiseParam{
+			{name: "Distance", options: []string{"F1", "F2", "F2−F1"}},
+			{name: "Metric", options: []string{"Euclidean", "Manhattan", "Chebyshev"}},
+			{name: "Jitter", value: 1},
+		},
+		new: func(seed int64, p []float64) noiseGenerator {
+			return worleyNoise{seed: uint64(seed), distance: int(p[0]), metric: int(p[1]), jitter: p[2]}
+		},
+	},
+	{
+		name: "White",
+		new: func(seed int64, p []float64) noiseGenerator {
+			return whiteNoise{seed: uint64(seed)}
+		},
+	},
+	{
+		name: "Blue",
+		params: []noiseParam{
+			{name: "Tile size", value: 64},
+		},
+		new: func(seed int64, p []float64) noiseGenerator {
+			return newBlueNoise(uint64(seed), max(8, min(128, int(p[0]))))
+		},
+	},
+}
+
+// hash2 hashes the lattice point i, j with seed into 64 random bits.
+func hash2(seed uint64, i, j int64) uint64 {
+	h := seed ^ uint64(i)*0x5205402b9270c86f ^ uint64(j)*0x598cd327003817b5
+	h ^= h >> 31
+	h *= 0x53a3f72deec546f5
+	h ^= h >> 29
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 32
+
+	return h
+}
+
+// unitHash maps a hash to [0, 1).
+func unitHash(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
+
+// openSimplex2 is the fast 2D variant of K.jpg's OpenSimplex2: simplex
+// noise over a triangular lattice, with gradients every 15°.
+type openSimplex2 struct {
+	seed uint64
+}
+
+const (
+	simplexSkew   = 0.366025403784439    // (√3 - 1) / 2
+	simplexUnskew = -0.21132486540518713 // (1/√3 - 1) / 2
+
+	// simplexNormalizer scales the sum of the contributions to [-1, 1].
+	simplexNormalizer = 0.01001634121365712
+)
+
+// simplexGradients are the 24 directions at 7.5° + k·15°, normalized.
+var simplexGradients = func() [24][2]float64 {
+	var g [24][2]float64
+	for k := range g {
+		a := (7.5 + 15*float64(k)) * math.Pi / 180
+		g[k] = [2]float64{math.Cos(a) / simplexNormalizer, math.Sin(a) / simplexNormalizer}
+	}
+	return g
+}()
+
+func (n openSimplex2) Noise2D(x, y float64) float64 {
+	// skew to the lattice of the triangles
+	s := simplexSkew * (x + y)
+	xs, ys := x+s, y+s
+	xsb, ysb := math.Floor(xs), math.Floor(ys)
+	xi, yi := xs-xsb, ys-ysb
+	i, j := int64(xsb), int64(ysb)
+
+	t := (xi + yi) * simplexUnskew
+	dx0, dy0 := xi+t, yi+t
+
+	contribution := func(i, j int64, dx, dy float64) float64 {
+		a := 0.5 - dx*dx - dy*dy
+		if a <= 0 {
+			return 0
+		}
+		g := simplexGradients[hash2(n.seed, i, j)%24]
+		return a * a * a * a * (g[0]*dx + g[1]*dy)
+	}
+
+	value := contribution(i, j, dx0, dy0)
+	value += contribution(i+1, j+1, dx0-(1+2*simplexUnskew), dy0-(1+2*simplexUnskew))
+	if dy0 > dx0 {
+		value += contribution(i, j+1, dx0-simplexUnskew, dy0-(simplexUnskew+1))
+	} else {
+		value += contribution(i+1, j, dx0-(simplexUnskew+1), dy0-simplexUnskew)
+	}
+
+	return value
+}
+
+// valueNoise interpolates random values at the integer lattice points.
+type valueNoise struct {
+	seed uint64
+
+	// interpolation is 0 for linear, 1 for cubic and 2 for quintic.
+	interpolation int
+
+	// period is how many cells the noise repeats after along x and y, 0
+	// meaning never.
+	period [2]int64
+}
+
+func (n valueNoise) Noise2D(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	i, j := int64(x0), int64(y0)
+
+	fade := func(t float64) float64 {
+		switch n.interpolation {
+		case 0:
+			return t
+		case 1:
+			return t * t * (3 - 2*t)
+		}
+		return t * t * t * (t*(6*t-15) + 10)
+	}
+	u, v := fade(x-x0), fade(y-y0)
+
+	value := func(i, j int64) float64 {
+		return 2*unitHash(hash2(n.seed, wrap(i, n.period[0]), wrap(j, n.period[1]))) - 1
+	}
+	a := value(i, j) + u*(value(i+1, j)-value(i, j))
+	b := value(i, j+1) + u*(value(i+1, j+1)-value(i, j+1))
+
+	return a + v*(b-a)
+}
+
+// worleyNoise is cellular noise: the distance to the nearest of random
+// feature points, one per lattice cell.
+type worleyNoise struct {
+	seed uint64
+
+	// distance is 0 for F1, the distance to the nearest point, 1 for F2,
+	// to the second nearest, and 2 for F2 - F1.
+	distance int
+
+	// metric is 0 for Euclidean, 1 for Manhattan and 2 for Chebyshev.
+	metric int
+
+	// jitter is how far the points stray from the center of their cells,
+	// from 0 for a grid to 1.
+	jitter float64
+
+	// period is how many cells the noise repeats after along x and y, 0
+	// meaning never.
+	period [2]int64
+}
+
+func (n worleyNoise) Noise2D(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	i, j := int64(x0), int64(y0)
+
+	f1, f2 := math.Inf(1), math.Inf(1)
+	for di := int64(-1); di <= 1; di++ {
+		for dj := int64(-1); dj <= 1; dj++ {
+			h := hash2(n.seed, wrap(i+di, n.period[0]), wrap(j+dj, n.period[1]))
+			px := float64(di) + 0.5 + n.jitter*(unitHash(h)-0.5)
+			py := float64(dj) + 0.5 + n.jitter*(unitHash(h*0x9e3779b97f4a7c15)-0.5)
+			dx, dy := math.Abs(px-(x-x0)), math.Abs(py-(y-y0))
+
+			var d float64
+			switch n.metric {
+			case 0:
+				d = math.Sqrt(dx*dx + dy*dy)
+			case 1:
+				d = dx + dy
+			default:
+				d = max(dx, dy)
+			}
+
+			if d < f1 {
+				f1, f2 = d, f1
+			} else if d < f2 {
+				f2 = d
+			}
+		}
+	}
+
+	d := f1
+	switch n.distance {
+	case 1:
+		d = f2
+	case 2:
+		d = f2 - f1
+	}
+
+	return 2*d - 1
+}
+
+// whiteNoise is an independent random value for each integer x, y.
+type whiteNoise struct {
+	seed uint64
+
+	// period is how many cells the noise repeats after along x and y, 0
+	// meaning never.
+	period [2]int64
+}
+
+func (n whiteNoise) Noise2D(x, y float64) float64 {
+	return 2*unitHash(hash2(n.seed, wrap(int64(math.Floor(x)), n.period[0]), wrap(int64(math.Floor(y)), n.period[1]))) - 1
+}
+
+// blueNoise is white noise without its low frequencies, sampled from a
+// tile made by the void and cluster method.
+type blueNoise struct {
+	size  int
+	ranks []float64
+}
+
+// blueNoiseTiles caches the tiles, which take a while to make, by seed and
+// size.
+var blueNoiseTiles sync.Map
+
+func newBlueNoise(seed uint64, size int) blueNoise {
+	key := [2]uint64{seed, uint64(size)}
+	if t, ok := blueNoiseTiles.Load(key); ok {
+		return t.(blueNoise)
+	}
+
+	t := blueNoise{size, voidAndCluster(seed, size)}
+	blueNoiseTiles.Store(key, t)
+
+	return t
+}
+
+func (n blueNoise) Noise2D(x, y float64) float64 {
+	i := int(math.Floor(x)) % n.size
+	j := int(math.Floor(y)) % n.size
+	if i < 0 {
+		i += n.size
+	}
+	if j < 0 {
+		j += n.size
+	}
+
+	return 2*n.ranks[j*n.size+i] - 1
+}
+
+// voidAndCluster ranks the pixels of a size×size tile as Ulichney's void
+// and cluster method does, returning the ranks scaled to [0, 1): from a
+// random pattern made even by moving the point in the tightest cluster to
+// the largest void, points are removed from the tightest clusters and then
+// added to the largest voids, in the order of their ranks.
+func voidAndCluster(seed uint64, size int) []float64 {
+	n := size * size
+	rng := rand.New(rand.NewPCG(seed, uint64(size)))
+
+	// the energy a point adds around it, wrapping around the tile
+	const sigma = 1.5
+	kernel := make([]float64, n)
+	for j := range size {
+		for i := range size {
+			dx, dy := float64(min(i, size-i)), float64(min(j, size-j))
+			kernel[j*size+i] = math.Exp(-(dx*dx + dy*dy) / (2 * sigma * sigma))
+		}
+	}
+
+	points := make([]bool, n)
+	energy := make([]float64, n)
+	toggle := func(p int, on bool) {
+		points[p] = on
+		s := 1.0
+		if !on {
+			s = -1
+		}
+		px, py := p%size, p/size
+		for j := range size {
+			for i := range size {
+				k := kernel[((j-py+size)%size)*size+(i-px+size)%size]
+				energy[j*size+i] += s * k
+			}
+		}
+	}
+
+	// the point with the most energy, or the empty pixel with the least
+	tightest := func() int {
+		best := -1
+		for p := range n {
+			if points[p] && (best < 0 || energy[p] > energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+	largest := func() int {
+		best := -1
+		for p := range n {
+			if !points[p] && (best < 0 || energy[p] < energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+
+	initial := max(1, n/10)
+	for _, p := range rng.Perm(n)[:initial] {
+		toggle(p, true)
+	}
+	for range n {
+		c := tightest()
+		toggle(c, false)
+		v := largest()
+		toggle(v, true)
+		if v == c {
+			break
+		}
+	}
+
+	ranks := make([]float64, n)
+	prototype := slices.Clone(points)
+
+	for r := initial - 1; r >= 0; r-- {
+		c := tightest()
+		toggle(c, false)
+		ranks[c] = float64(r) / float64(n)
+	}
+
+	for p := range n {
+		if prototype[p] != points[p] {
+			toggle(p, prototype[p])
+		}
+	}
+	for r := initial; r < n; r++ {
+		v := largest()
+		toggle(v, true)
+		ranks[v] = float64(r) / float64(n)
+	}
+
+	return ranks
+}
@@ -0,0 +1,1024 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	dialog2 "github.com/sqweek/dialog"
+)
+
+func perlinPage(w fyne.Window) fyne.CanvasObject {
+	whiteBackground := canvas.NewImageFromImage(newWhiteBackground(1200, 1200))
+	whiteBackground.ScaleMode = canvas.ImageScaleFastest
+	whiteBackground.FillMode = canvas.ImageFillContain
+
+	var graph = image.NewNRGBA64(image.Rect(0, 0, 1200, 1200))
+
+	var algorithm = noiseAlgorithms[0]
+	var seed int64 = 123456
+	var colorMode = "Gradient"
+	var ramp = gradient{stops: slices.Clone(gradientPresets["Grayscale"]), oklab: true}
+	var individualRefresh = false
+	var renderProgress = true
+	var useWhiteBackground = true
+	var tiling = "Off"
+	var showTiled = false
+
+	var xDivide = 15.0
+	var zDivide = 15.0
+	var intensify = 1.0
+
+	// params are the settings of each algorithm, kept when switching
+	// between them.
+	params := make(map[string][]float64)
+	for _, alg := range noiseAlgorithms {
+		for _, p := range alg.params {
+			params[alg.name] = append(params[alg.name], p.value)
+		}
+	}
+
+	var seedInput = widget.NewEntry()
+	seedInput.SetText(strconv.FormatInt(int64(seed), 10))
+	seedInput.OnChanged = func(s string) {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return
+		}
+		seed = i
+	}
+
+	// paramsBox shows the settings of the selected algorithm.
+	paramsBox := container.NewGridWithColumns(4)
+	showParams := func() {
+		paramsBox.RemoveAll()
+		for i, p := range algorithm.params {
+			values := params[algorithm.name]
+
+			var input fyne.CanvasObject
+			if p.options != nil {
+				sel := widget.NewSelect(p.options, nil)
+				sel.SetSelectedIndex(int(values[i]))
+				sel.OnChanged = func(string) {
+					values[i] = float64(sel.SelectedIndex())
+				}
+				input = sel
+			} else {
+				entry := widget.NewEntry()
+				entry.SetText(strconv.FormatFloat(values[i], 'f', -1, 64))
+				entry.OnChanged = func(s string) {
+					v, err := strconv.ParseFloat(s, 64)
+					if err != nil {
+						return
+					}
+					values[i] = v
+				}
+				input = entry
+			}
+
+			paramsBox.Add(container.NewBorder(nil, nil, widget.NewLabel(p.name), nil, input))
+		}
+		paramsBox.Add(container.NewBorder(nil, nil, widget.NewLabel("Seed"), nil, seedInput))
+	}
+
+	var algorithmSelect = widget.NewSelect(nil, func(s string) {
+		for _, alg := range noiseAlgorithms {
+			if alg.name == s {
+				algorithm = alg
+			}
+		}
+		showParams()
+	})
+	for _, alg := range noiseAlgorithms {
+		algorithmSelect.Options = append(algorithmSelect.Options, alg.name)
+	}
+	algorithmSelect.SetSelected(algorithm.name)
+
+	img := canvas.NewImageFromImage(graph)
+	img.ScaleMode = canvas.ImageScaleFastest
+	img.FillMode = canvas.ImageFillContain
+
+	var pixelPerSecond = widget.NewLabel("N/A pps")
+	var rendering = widget.NewLabel("Rendering... N/A%")
+
+	var codeBlock = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+
+	fractal := fractalNoise{mode: fractalNone, octaves: 5, lacunarity: 2, gain: 0.5}
+
+	fractalSelect := widget.NewSelect(fractalModes, nil)
+	fractalSelect.OnChanged = func(string) {
+		fractal.mode = fractalSelect.SelectedIndex()
+	}
+	fractalSelect.SetSelectedIndex(fractal.mode)
+
+	octavesInput := widget.NewEntry()
+	octavesInput.SetText(strconv.Itoa(fractal.octaves))
+	octavesInput.OnChanged = func(s string) {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 || i > 16 {
+			return
+		}
+		fractal.octaves = i
+	}
+
+	lacunarityInput := widget.NewEntry()
+	lacunarityInput.SetText(strconv.FormatFloat(fractal.lacunarity, 'f', 2, 64))
+	lacunarityInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		fractal.lacunarity = i
+	}
+
+	gainInput := widget.NewEntry()
+	gainInput.SetText(strconv.FormatFloat(fractal.gain, 'f', 2, 64))
+	gainInput.OnChanged = func(s string) {
+		// a gain that isn't positive can sum the amplitudes to 0
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(i > 0) || math.IsInf(i, 0) {
+			return
+		}
+		fractal.gain = i
+	}
+
+	warp := domainWarp{amplitude: 0, frequency: 1, iterations: 2}
+
+	warpAmplitudeInput := widget.NewEntry()
+	warpAmplitudeInput.SetText(strconv.FormatFloat(warp.amplitude, 'f', 2, 64))
+	warpAmplitudeInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		warp.amplitude = i
+	}
+
+	warpFrequencyInput := widget.NewEntry()
+	warpFrequencyInput.SetText(strconv.FormatFloat(warp.frequency, 'f', 2, 64))
+	warpFrequencyInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		warp.frequency = i
+	}
+
+	warpIterationsInput := widget.NewEntry()
+	warpIterationsInput.SetText(strconv.Itoa(warp.iterations))
+	warpIterationsInput.OnChanged = func(s string) {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i > 8 {
+			return
+		}
+		warp.iterations = i
+	}
+
+	// octaveStrip previews each octave of the last render on its own.
+	octaveStrip := container.NewHBox()
+	showOctaves := func(f fractalNoise) {
+		octaveStrip.RemoveAll()
+		if f.mode == fractalNone {
+			return
+		}
+
+		amp := 1.0
+		for i := range f.octaves {
+			preview := canvas.NewImageFromImage(f.octavePreview(i, 96, graph.Rect.Dx(), graph.Rect.Dy(), xDivide, zDivide))
+			preview.FillMode = canvas.ImageFillOriginal
+			label := widget.NewLabel(fmt.Sprintf("%d: ×%.3g", i+1, amp))
+			octaveStrip.Add(container.NewVBox(preview, label))
+			amp *= f.gain
+		}
+	}
+
+	norm := normalization{mode: normalizeFixed, lo: -1, hi: 1, percentile: 1}
+
+	// values are the noise of the last render, before normalization, so
+	// that changing it doesn't render again.
+	var values []float64
+
+	histogramImg := canvas.NewImageFromImage(image.NewNRGBA(image.Rect(0, 0, 256, 96)))
+	histogramImg.FillMode = canvas.ImageFillOriginal
+	histogramLabel := widget.NewLabel("")
+
+	// paintMu keeps the tiles of a render and a repaint from painting over
+	// each other.
+	var paintMu sync.Mutex
+
+	// colorTable is the gradient to paint with, nil for raw grayscale.
+	colorTable := func() []color.NRGBA64 {
+		if colorMode != "Gradient" {
+			return nil
+		}
+		return ramp.table(4096)
+	}
+
+	// paintRect colors the pixels of r in dst from the values of a render,
+	// in grayscale if colors is nil.
+	paintRect := func(dst *image.NRGBA64, values []float64, r image.Rectangle, lo, hi float64, colors []color.NRGBA64) {
+		width := dst.Rect.Dx()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				t := normalizeValue(values[y*width+x], lo, hi)
+				if colors != nil {
+					dst.SetNRGBA64(x, y, colors[int(t*float64(len(colors)-1)+0.5)])
+				} else {
+					dst.SetNRGBA64(x, y, color.NRGBA64{R: uint16(t * 0xffff), G: uint16(t * 0xffff), B: uint16(t * 0xffff), A: 0xffff})
+				}
+			}
+		}
+	}
+
+	output := "Color"
+	maps := mapSettings{scale: 30, azimuth: 315, altitude: 45, radius: 16}
+
+	// showLocked puts the render, or a map derived from it, or four copies
+	// of either to check that it tiles, on the screen. The render goroutine
+	// swaps values and graph, so paintMu must be held.
+	showLocked := func() {
+		var out image.Image = graph
+		if output != "Color" && len(values) == graph.Rect.Dx()*graph.Rect.Dy() {
+			lo, hi := norm.bounds(values)
+			out = newHeightField(values, graph.Rect.Dx(), graph.Rect.Dy(), lo, hi).derived(output, maps)
+		}
+		if showTiled {
+			out = tilePreview(out)
+		}
+		img.Image = out
+		img.Refresh()
+	}
+
+	show := func() {
+		paintMu.Lock()
+		defer paintMu.Unlock()
+		showLocked()
+	}
+
+	paint := func() {
+		paintMu.Lock()
+		defer paintMu.Unlock()
+		if len(values) != graph.Rect.Dx()*graph.Rect.Dy() {
+			return
+		}
+
+		lo, hi := norm.bounds(values)
+		paintRect(graph, values, graph.Rect, lo, hi, colorTable())
+		showLocked()
+
+		_, below, above := histogram(values, 1, lo, hi)
+		histogramImg.Image = histogramImage(values, 256, 96, lo, hi)
+		histogramImg.Refresh()
+		histogramLabel.SetText(fmt.Sprintf("%.3g to %.3g\n%.1f%% clamped", lo, hi, float64(below+above)*100/float64(len(values))))
+	}
+
+	normSelect := widget.NewSelect(normalizeModes, nil)
+	normSelect.SetSelectedIndex(norm.mode)
+	normSelect.OnChanged = func(string) {
+		norm.mode = normSelect.SelectedIndex()
+		paint()
+	}
+
+	normLoInput := widget.NewEntry()
+	normLoInput.SetText(strconv.FormatFloat(norm.lo, 'f', 2, 64))
+	normLoInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		norm.lo = i
+		paint()
+	}
+
+	normHiInput := widget.NewEntry()
+	normHiInput.SetText(strconv.FormatFloat(norm.hi, 'f', 2, 64))
+	normHiInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		norm.hi = i
+		paint()
+	}
+
+	percentileInput := widget.NewEntry()
+	percentileInput.SetText(strconv.FormatFloat(norm.percentile, 'f', -1, 64))
+	percentileInput.OnSubmitted = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil || i < 0 || i >= 50 {
+			return
+		}
+		norm.percentile = i
+		paint()
+	}
+
+	// cancel stops the render or export in progress, if any. Playback
+	// starts renders off the UI goroutine, so cancelMu guards it.
+	cancel := context.CancelFunc(func() {})
+	var cancelMu sync.Mutex
+
+	stopRender := func() {
+		cancelMu.Lock()
+		defer cancelMu.Unlock()
+		cancel()
+	}
+
+	// restart stops the render or export in progress, returning the
+	// context of the next one.
+	restart := func() (context.Context, context.CancelFunc) {
+		cancelMu.Lock()
+		defer cancelMu.Unlock()
+		cancel()
+		ctx, stop := context.WithCancel(context.Background())
+		cancel = stop
+		return ctx, stop
+	}
+
+	var cancelButton *widget.Button
+	cancelButton = widget.NewButton("Cancel", func() {
+		stopRender()
+		cancelButton.Disable()
+	})
+	cancelButton.Disable()
+
+	// frames is the length of the animation, 1 meaning a still, and speed
+	// how far each frame moves along the time axis of the noise.
+	var frames = 1
+	var frame = 0
+	var speed = 0.05
+	var loop = false
+
+	// newGenerator returns the noise of each frame, sampled at xd, zd, with
+	// the settings as they are now, so that changing them midway doesn't
+	// change the rest of an export.
+	newGenerator := func() func(frame int, width, height int) (p noiseGenerator, f fractalNoise, xd, zd float64) {
+		algorithm, v, seed := algorithm, slices.Clone(params[algorithm.name]), seed
+		frames, speed, loop := frames, speed, loop
+		fractal, warp, tiling, xDivide, zDivide := fractal, warp, tiling, xDivide, zDivide
+		return func(frame int, width, height int) (p noiseGenerator, f fractalNoise, xd, zd float64) {
+			base := algorithm.new(seed, v)
+			if frames > 1 {
+				period := 0.0
+				if loop {
+					period = float64(frames) * speed
+				}
+				base = animate(base, float64(frame)*speed, period)
+			}
+
+			f = fractal
+			f.base = base
+			d := warp
+			d.base, d.warp = f, f
+
+			// tileable noise repeats over the image, which has to span a whole
+			// number of samples along the axes it wraps around
+			xd, zd = xDivide, zDivide
+			var px, py float64
+			if tiling == "X" || tiling == "Both" {
+				px = max(1, math.Round(float64(width)/xDivide))
+				xd = float64(width) / px
+			}
+			if tiling == "Y" || tiling == "Both" {
+				py = max(1, math.Round(float64(height)/zDivide))
+				zd = float64(height) / py
+			}
+
+			return tile(d, px, py), f, xd, zd
+		}
+	}
+
+	// render renders the current frame, returning a channel closed once it
+	// is done or cancelled.
+	render := func() <-chan struct{} {
+		ctx, stop := restart()
+
+		t := time.Now()
+		paintMu.Lock()
+		width, height := graph.Rect.Dx(), graph.Rect.Dy()
+		paintMu.Unlock()
+		p, f, xd, zd := newGenerator()(frame, width, height)
+
+		totalPixels := width * height
+
+		if algorithm.name == "Perlin" && f.mode == fractalNone && (warp.amplitude == 0 || warp.iterations == 0) && tiling == "Off" && frames == 1 {
+			v := params[algorithm.name]
+			codeBlock.SetText(genCode(v[0], v[1], int32(v[2]), seed, xDivide, zDivide, intensify, width, height))
+		} else {
+			codeBlock.SetText("")
+		}
+
+		next := make([]float64, totalPixels)
+		done := renderNoise(ctx, p, next, width, height, xd, zd, intensify)
+		cancelButton.Enable()
+
+		// tiles are shown in the fixed range as they come, the bounds of
+		// the other modes being known once every tile is
+		colors := colorTable()
+
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+
+			var rendered int
+			for r := range done {
+				rendered += r.Dx() * r.Dy()
+				if individualRefresh {
+					paintMu.Lock()
+					if len(next) == graph.Rect.Dx()*graph.Rect.Dy() {
+						paintRect(graph, next, r, norm.lo, norm.hi, colors)
+					}
+					paintMu.Unlock()
+					img.Refresh()
+				}
+				if renderProgress {
+					rendering.SetText(fmt.Sprintf("Rendering... %d%%", rendered*100/totalPixels))
+				}
+			}
+
+			if ctx.Err() != nil {
+				rendering.SetText(fmt.Sprintf("Cancelled at %d%%", rendered*100/totalPixels))
+				return
+			}
+			stop()
+			cancelButton.Disable()
+
+			paintMu.Lock()
+			values = next
+			paintMu.Unlock()
+			paint()
+
+			timeTaken := time.Since(t)
+
+			showOctaves(f)
+
+			rendering.SetText("Rendering... 100%")
+			pixelPerSecond.SetText(fmt.Sprintf("%d px/s (%.2fs)", int(float64(totalPixels)/math.Max(timeTaken.Seconds(), 1e-3)), timeTaken.Seconds()))
+		}()
+
+		return finished
+	}
+
+	var resetButton = widget.NewButton("Render", func() {
+		render()
+	})
+
+	timeLabel := widget.NewLabel("Frame 1/1")
+	timeSlider := widget.NewSlider(0, 0)
+	timeSlider.Step = 1
+	timeSlider.OnChanged = func(v float64) {
+		frame = int(v)
+		timeLabel.SetText(fmt.Sprintf("Frame %d/%d", frame+1, frames))
+	}
+	timeSlider.OnChangeEnded = func(float64) {
+		render()
+	}
+
+	framesInput := widget.NewEntry()
+	framesInput.SetText(strconv.Itoa(frames))
+	framesInput.OnChanged = func(s string) {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 {
+			return
+		}
+		frames = i
+		timeSlider.Max = float64(frames - 1)
+		timeSlider.SetValue(math.Min(timeSlider.Value, timeSlider.Max))
+		timeSlider.Refresh()
+		timeLabel.SetText(fmt.Sprintf("Frame %d/%d", frame+1, frames))
+	}
+
+	speedInput := widget.NewEntry()
+	speedInput.SetText(strconv.FormatFloat(speed, 'f', -1, 64))
+	speedInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil || i <= 0 {
+			return
+		}
+		speed = i
+	}
+
+	loopCheck := widget.NewCheck("Seamless loop", func(b bool) {
+		loop = b
+	})
+
+	// stopPlaying ends playback, if playing, and playbackDone is closed
+	// once it has rendered its last frame.
+	var stopPlaying chan struct{}
+	playbackDone := make(chan struct{})
+	close(playbackDone)
+	var playButton *widget.Button
+
+	// pause stops playback, returning a channel closed once it has.
+	pause := func() <-chan struct{} {
+		if stopPlaying != nil {
+			close(stopPlaying)
+			stopPlaying = nil
+			playButton.SetIcon(theme.MediaPlayIcon())
+		}
+		return playbackDone
+	}
+
+	playButton = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		if stopPlaying != nil {
+			pause()
+			return
+		}
+		if frames < 2 {
+			return
+		}
+
+		stop, done := make(chan struct{}), make(chan struct{})
+		stopPlaying, playbackDone = stop, done
+		playButton.SetIcon(theme.MediaPauseIcon())
+
+		// frames show as fast as they render, up to animationFPS
+		go func() {
+			defer close(done)
+			tick := time.NewTicker(time.Second / animationFPS)
+			defer tick.Stop()
+			for {
+				timeSlider.SetValue(float64((frame + 1) % frames))
+				<-render()
+				<-tick.C
+				select {
+				case <-stop:
+					return
+				default:
+				}
+			}
+		}()
+	})
+
+	formatSelect := widget.NewSelect([]string{"GIF", "APNG", "PNG sequence"}, nil)
+	formatSelect.SetSelected("GIF")
+
+	// exportAnimation renders every frame to path, normalized as the
+	// first one is so that the brightness doesn't flicker. It takes the
+	// settings on the UI goroutine, returning the export to run off it.
+	exportAnimation := func(path, format string) func(ctx context.Context) error {
+		width, height := graph.Rect.Dx(), graph.Rect.Dy()
+		colors := colorTable()
+		norm, intensify, frames := norm, intensify, frames
+		generator := newGenerator()
+
+		var palette color.Palette
+		for i := range 256 {
+			if colors != nil {
+				palette = append(palette, colors[i*(len(colors)-1)/255])
+			} else {
+				palette = append(palette, color.Gray{uint8(i)})
+			}
+		}
+
+		return func(ctx context.Context) error {
+			anim := &gif.GIF{}
+			var apng *apngEncoder
+			if format == "APNG" {
+				file, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				apng = newAPNGEncoder(file, frames, animationFPS)
+			}
+
+			var lo, hi float64
+			for i := range frames {
+				p, _, xd, zd := generator(i, width, height)
+				v := make([]float64, width*height)
+				for range renderNoise(ctx, p, v, width, height, xd, zd, intensify) {
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if i == 0 {
+					lo, hi = norm.bounds(v)
+				}
+
+				switch format {
+				case "GIF":
+					out := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+					for k, x := range v {
+						out.Pix[k] = uint8(normalizeValue(x, lo, hi)*255 + 0.5)
+					}
+					anim.Image = append(anim.Image, out)
+					anim.Delay = append(anim.Delay, 100/animationFPS)
+				case "APNG":
+					out := image.NewNRGBA64(image.Rect(0, 0, width, height))
+					paintRect(out, v, out.Rect, lo, hi, colors)
+					if err := apng.frame(out); err != nil {
+						return err
+					}
+				default:
+					out := image.NewNRGBA64(image.Rect(0, 0, width, height))
+					paintRect(out, v, out.Rect, lo, hi, colors)
+					file, err := os.Create(fmt.Sprintf("%s_%04d.png", strings.TrimSuffix(path, filepath.Ext(path)), i+1))
+					if err != nil {
+						return err
+					}
+					err = png.Encode(file, out)
+					file.Close()
+					if err != nil {
+						return err
+					}
+				}
+
+				rendering.SetText(fmt.Sprintf("Exporting... %d/%d", i+1, frames))
+			}
+
+			if format == "GIF" {
+				file, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				return gif.EncodeAll(file, anim)
+			}
+
+			return nil
+		}
+	}
+
+	exportAnimationButton := widget.NewButton("Export animation", func() {
+		format := formatSelect.Selected
+		var p string
+		var err error
+		if format == "GIF" {
+			p, err = dialog2.File().Filter("GIF (.gif)", "gif").Save()
+		} else {
+			p, err = dialog2.File().Filter("PNG (.png)", "png").Save()
+		}
+		if err != nil {
+			return
+		}
+
+		// playback would restart its render over the export
+		export := exportAnimation(p, format)
+		stopped := pause()
+
+		go func() {
+			<-stopped
+			ctx, stop := restart()
+			defer stop()
+			cancelButton.Enable()
+			err := export(ctx)
+			cancelButton.Disable()
+			switch {
+			case ctx.Err() != nil:
+				rendering.SetText("Export cancelled")
+			case err != nil:
+				dialog.ShowError(err, w)
+			default:
+				rendering.SetText("Exported")
+			}
+		}()
+	})
+
+	animationRow := container.NewBorder(nil, nil, container.NewHBox(
+		playButton,
+		container.NewGridWithColumns(2, widget.NewLabel("Frames"), framesInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Speed"), speedInput),
+		loopCheck,
+	), container.NewHBox(timeLabel, formatSelect, exportAnimationButton), timeSlider)
+
+	var colorModeSelect = widget.NewSelect([]string{"Gradient", "Raw grayscale"}, func(s string) {
+		colorMode = s
+	})
+	colorModeSelect.SetSelected(colorMode)
+	gradientButton := widget.NewButton("Gradient…", func() {
+		dialog.NewCustom("Gradient", "Close", gradientEditor(&ramp, w), w).Show()
+	})
+	cM := container.NewBorder(nil, nil, widget.NewLabel("Color"), gradientButton, colorModeSelect)
+	rM := widget.NewCheck("Real time reload", func(b bool) {
+		individualRefresh = b
+	})
+	pM := widget.NewCheck("Render progress", func(b bool) {
+		renderProgress = b
+	})
+	wB := widget.NewCheck("White background", func(b bool) {
+		useWhiteBackground = b
+		if !b {
+			whiteBackground.Hide()
+		} else {
+			if graph.Rect.Max != whiteBackground.Image.Bounds().Max {
+				whiteBackground.Image = newWhiteBackground(graph.Rect.Dx(), graph.Rect.Dy())
+			}
+			whiteBackground.Show()
+		}
+	})
+	wB.SetChecked(useWhiteBackground)
+	pM.SetChecked(renderProgress)
+
+	tilingSelect := widget.NewSelect([]string{"Off", "X", "Y", "Both"}, func(s string) {
+		tiling = s
+	})
+	tilingSelect.SetSelected(tiling)
+	tiledCheck := widget.NewCheck("2×2 preview", func(b bool) {
+		showTiled = b
+		show()
+	})
+
+	var xDivideInput = widget.NewEntry()
+	xDivideInput.SetText(strconv.FormatFloat(xDivide, 'f', 2, 64))
+	xDivideInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		xDivide = i
+	}
+
+	var zDivideInput = widget.NewEntry()
+	zDivideInput.SetText(strconv.FormatFloat(zDivide, 'f', 2, 64))
+	zDivideInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		zDivide = i
+	}
+
+	var intensifyInput = widget.NewEntry()
+	intensifyInput.SetText(strconv.FormatFloat(intensify, 'f', 2, 64))
+	intensifyInput.OnChanged = func(s string) {
+		i, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		intensify = i
+	}
+
+	topBottom := container.NewHBox(
+		cM,
+		container.NewGridWithColumns(3, widget.NewLabel("Focus"), xDivideInput, zDivideInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Intensity"), intensifyInput),
+		wB,
+		container.NewBorder(nil, nil, widget.NewLabel("Tileable"), nil, tilingSelect),
+		tiledCheck,
+		rM,
+		pM,
+		widget.NewButton("Resize", func() {
+			var newWidth = graph.Rect.Dx()
+			var newHeight = graph.Rect.Dy()
+
+			var widthInput = widget.NewEntry()
+			widthInput.SetText(strconv.FormatInt(int64(newWidth), 10))
+			widthInput.OnChanged = func(s string) {
+				i, err := strconv.ParseInt(s, 10, 64)
+				if err != nil || i < 1 {
+					return
+				}
+				newWidth = int(i)
+			}
+
+			var heightInput = widget.NewEntry()
+			heightInput.SetText(strconv.FormatInt(int64(newHeight), 10))
+			heightInput.OnChanged = func(s string) {
+				i, err := strconv.ParseInt(s, 10, 64)
+				if err != nil || i < 1 {
+					return
+				}
+				newHeight = int(i)
+			}
+
+			width := container.NewGridWithColumns(2, widget.NewLabel("Width"), widthInput)
+			height := container.NewGridWithColumns(2, widget.NewLabel("Height"), heightInput)
+
+			dialog := dialog.NewCustomWithoutButtons("Resize", container.NewCenter(container.NewVBox(width, height)), w)
+			dialog.SetButtons([]fyne.CanvasObject{
+				widget.NewButton("Resize", func() {
+					if useWhiteBackground {
+						whiteBackground.Image = newWhiteBackground(newWidth, newHeight)
+						whiteBackground.Refresh()
+					}
+
+					stopRender()
+					cancelButton.Disable()
+					paintMu.Lock()
+					graph = image.NewNRGBA64(image.Rect(0, 0, newWidth, newHeight))
+					paintMu.Unlock()
+					show()
+					dialog.Hide()
+				}),
+			})
+
+			dialog.Show()
+		}),
+		widget.NewButton("Export", func() {
+			p, err := dialog2.File().Filter("PNG (.png)", "png").Filter("JPEG (.jpg/.jpeg/.jfif)", ".jpg", ".jpeg", ".jfif").Save()
+			if err != nil {
+				return
+			}
+			extI := strings.LastIndex(p, ".")
+			var ext string
+			if extI != -1 {
+				ext = p[extI:]
+			}
+
+			file, _ := os.Create(p)
+
+			switch ext {
+			case "jpeg", "jpg", "jfif":
+				jpeg.Encode(file, graph, nil)
+			default:
+				png.Encode(file, graph)
+			}
+
+			file.Close()
+		}),
+		widget.NewButton("Heightmap…", func() {
+			formatSelect := widget.NewSelect([]string{"16-bit PNG", "R32", "OBJ", "STL"}, nil)
+			formatSelect.SetSelected("16-bit PNG")
+
+			scale := 100.0
+			scaleInput := widget.NewEntry()
+			scaleInput.SetText(strconv.FormatFloat(scale, 'f', -1, 64))
+			scaleInput.OnChanged = func(s string) {
+				i, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return
+				}
+				scale = i
+			}
+
+			step := 4
+			stepInput := widget.NewEntry()
+			stepInput.SetText(strconv.Itoa(step))
+			stepInput.OnChanged = func(s string) {
+				i, err := strconv.Atoi(s)
+				if err != nil || i < 1 {
+					return
+				}
+				step = i
+			}
+
+			// the derived maps are written next to the heightmap, named after
+			// it
+			withMaps := false
+			mapsCheck := widget.NewCheck("Normal, hillshade, slope and AO maps", func(b bool) {
+				withMaps = b
+			})
+
+			form := container.NewVBox(
+				container.NewGridWithColumns(2, widget.NewLabel("Format"), formatSelect),
+				container.NewGridWithColumns(2, widget.NewLabel("Vertical scale"), scaleInput),
+				container.NewGridWithColumns(2, widget.NewLabel("Decimation"), stepInput),
+				mapsCheck,
+			)
+
+			dialog.ShowCustomConfirm("Heightmap", "Export", "Cancel", form, func(ok bool) {
+				if !ok {
+					return
+				}
+
+				paintMu.Lock()
+				v := values
+				paintMu.Unlock()
+				if len(v) == 0 || len(v) != graph.Rect.Dx()*graph.Rect.Dy() {
+					dialog.ShowError(errors.New("render the noise first"), w)
+					return
+				}
+
+				format := formatSelect.Selected
+				var p string
+				var err error
+				switch format {
+				case "16-bit PNG":
+					p, err = dialog2.File().Filter("PNG (.png)", "png").Save()
+				case "R32":
+					p, err = dialog2.File().Filter("Raw float32 (.r32/.raw)", "r32", "raw").Save()
+				case "OBJ":
+					p, err = dialog2.File().Filter("Wavefront OBJ (.obj)", "obj").Save()
+				case "STL":
+					p, err = dialog2.File().Filter("STL (.stl)", "stl").Save()
+				}
+				if err != nil {
+					return
+				}
+
+				// heights are normalized as the preview is
+				lo, hi := norm.bounds(v)
+				field := newHeightField(v, graph.Rect.Dx(), graph.Rect.Dy(), lo, hi)
+
+				file, err := os.Create(p)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				defer file.Close()
+
+				switch format {
+				case "16-bit PNG":
+					err = png.Encode(file, field.gray16())
+				case "R32":
+					err = field.writeR32(file)
+				case "OBJ":
+					err = field.writeOBJ(file, scale, step)
+				case "STL":
+					err = field.writeSTL(file, scale, step)
+				}
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+
+				if !withMaps {
+					return
+				}
+				for _, m := range []struct{ suffix, output string }{
+					{"normal_gl", "Normal (OpenGL)"},
+					{"normal_dx", "Normal (DirectX)"},
+					{"hillshade", "Hillshade"},
+					{"slope", "Slope"},
+					{"ao", "Ambient occlusion"},
+				} {
+					file, err := os.Create(fmt.Sprintf("%s_%s.png", strings.TrimSuffix(p, filepath.Ext(p)), m.suffix))
+					if err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					err = png.Encode(file, field.derived(m.output, maps))
+					file.Close()
+					if err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+				}
+			}, w)
+		}),
+	)
+
+	fractalRow := container.NewHBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Fractal"), nil, fractalSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Octaves"), octavesInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Lacunarity"), lacunarityInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Gain"), gainInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Warp"), warpAmplitudeInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Warp frequency"), warpFrequencyInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Warp iterations"), warpIterationsInput),
+	)
+
+	normRow := container.NewHBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Normalize"), nil, normSelect),
+		container.NewGridWithColumns(3, widget.NewLabel("Range"), normLoInput, normHiInput),
+		container.NewGridWithColumns(2, widget.NewLabel("Clip %"), percentileInput),
+	)
+
+	// mapInput is an entry for a setting of the derived maps.
+	mapInput := func(v *float64) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(strconv.FormatFloat(*v, 'f', -1, 64))
+		e.OnSubmitted = func(s string) {
+			i, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return
+			}
+			*v = i
+			show()
+		}
+		return e
+	}
+
+	outputSelect := widget.NewSelect(mapOutputs, func(s string) {
+		output = s
+		show()
+	})
+	outputSelect.SetSelected(output)
+
+	mapsRow := container.NewHBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Output"), nil, outputSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Height scale"), mapInput(&maps.scale)),
+		container.NewGridWithColumns(3, widget.NewLabel("Sun"), mapInput(&maps.azimuth), mapInput(&maps.altitude)),
+		container.NewGridWithColumns(2, widget.NewLabel("AO radius"), mapInput(&maps.radius)),
+	)
+
+	var top = container.NewBorder(nil, container.NewVBox(topBottom, fractalRow, normRow, mapsRow, animationRow), container.NewBorder(nil, nil, widget.NewLabel("Algorithm"), nil, algorithmSelect), container.NewVBox(resetButton, cancelButton), paramsBox)
+
+	return container.NewBorder(top, container.NewVBox(container.NewHScroll(octaveStrip), container.NewHBox(container.NewBorder(nil, pixelPerSecond, nil, nil,
+		container.NewBorder(widget.NewLabel("Code: "), nil, nil, nil, codeBlock),
+	), layout.NewSpacer(), container.NewVBox(layout.NewSpacer(), rendering))), nil, container.NewVBox(widget.NewLabel("Histogram"), histogramImg, histogramLabel), container.NewStack(whiteBackground, img))
+}
+
+func genCode(a, b float64, i int32, seed int64, xd, zd, is float64, w, h int) string {
+	return fmt.Sprintf("import \"github.com/aquilax/go-perlin\"\n\nvar p = perlin.NewPerlin(%f, %f, %d, %d)\nfor x := 0; x < %d; x++ {\n\tfor y := 0; y < %d; y++ {\n\t\tvar value = p.Perlin2D(x/%f, y/%f)*%f\n\t}\n}", a, b, i, seed, w, h, xd, zd, is)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+)
+
+// normalizeModes are the ways the Noise tab maps the values of a render to
+// [0, 1] before coloring them.
+var normalizeModes = []string{"Fixed range", "Auto min/max", "Percentile"}
+
+const (
+	normalizeFixed = iota
+	normalizeAuto
+	normalizePercentile
+)
+
+// normalization maps noise values to [0, 1], clamping those outside of the
+// range: lo to hi when fixed, the extremes of the render when auto, and
+// the percentile-th to the (100-percentile)-th percentile otherwise.
+type normalization struct {
+	mode       int
+	lo, hi     float64
+	percentile float64
+}
+
+// bounds returns the values mapped to 0 and 1, ignoring NaNs and
+// infinities.
+func (n normalization) bounds(values []float64) (float64, float64) {
+	if n.mode == normalizeFixed {
+		return n.lo, n.hi
+	}
+
+	s := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			s = append(s, v)
+		}
+	}
+	if len(s) == 0 {
+		return n.lo, n.hi
+	}
+
+	if n.mode == normalizeAuto {
+		return slices.Min(s), slices.Max(s)
+	}
+
+	// quantile sorts on each call, which is slow for a whole render
+	slices.Sort(s)
+	at := func(p float64) float64 {
+		h := p * float64(len(s)-1)
+		i := int(h)
+		if i+1 >= len(s) {
+			return s[len(s)-1]
+		}
+		return s[i] + (h-float64(i))*(s[i+1]-s[i])
+	}
+	p := max(0, min(50, n.percentile)) / 100
+
+	return at(p), at(1 - p)
+}
+
+// normalizeValue maps v to [0, 1], given the bounds of the render, and
+// NaN, or values infinite bounds can't place, to 0.
+func normalizeValue(v, lo, hi float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	if hi <= lo {
+		if v >= hi {
+			return 1
+		}
+		return 0
+	}
+
+	t := (v - lo) / (hi - lo)
+	if math.IsNaN(t) {
+		return 0
+	}
+
+	return max(0, min(1, t))
+}
+
+// histogram counts values into bins evenly spaced over [lo, hi], returning
+// how many fell outside of it too.
+func histogram(values []float64, bins int, lo, hi float64) (counts []int, below, above int) {
+	counts = make([]int, bins)
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+		case v < lo:
+			below++
+		case v > hi:
+			above++
+		case hi <= lo:
+			counts[0]++
+		default:
+			b := int((v - lo) / (hi - lo) * float64(bins))
+			counts[min(bins-1, max(0, b))]++
+		}
+	}
+
+	return counts, below, above
+}
+
+// histogramImage draws the values of a render over their range, as one bar
+// per pixel column, and the bounds they are normalized to as red lines.
+func histogramImage(values []float64, w, h int, lo, hi float64) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	a, b := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			a, b = min(a, v, lo), max(b, v, hi)
+		}
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) || b <= a {
+		return img
+	}
+
+	counts, _, _ := histogram(values, w, a, b)
+	peak := max(1, slices.Max(counts))
+
+	for x, c := range counts {
+		v := a + (float64(x)+0.5)/float64(w)*(b-a)
+		bar := color.NRGBA{0x20, 0x60, 0xd0, 0xff}
+		if v < lo || v > hi {
+			// clamped to the ends of the gradient
+			bar = color.NRGBA{0x90, 0x90, 0x90, 0xff}
+		}
+		top := h - c*h/peak
+		for y := top; y < h; y++ {
+			img.SetNRGBA(x, y, bar)
+		}
+	}
+
+	for _, bound := range []float64{lo, hi} {
+		x := int((bound - a) / (b - a) * float64(w-1))
+		for y := range h {
+			img.SetNRGBA(x, y, color.NRGBA{0xe0, 0x20, 0x20, 0xff})
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// piecewise is y = { cond: expr, cond: expr, default }, or the same written
+// with ternaries, y = cond ? expr : cond ? expr : default. Each piece is
+// drawn on its own, so that jumps aren't joined, with open and closed
+// markers where pieces end.
+type piecewise struct {
+	pieces []piece
+
+	// exact are the values of x where conditions such as x < 2 or x == 1
+	// change, which are sampled exactly rather than found by bisection.
+	exact []float64
+}
+
+type piece struct {
+	// cond is nil for the default piece.
+	cond *govaluate.EvaluableExpression
+	expr *govaluate.EvaluableExpression
+}
+
+// isPiecewise reports whether str is y = {cond: expr, ...} or a y = with a
+// ternary at the top level.
+func isPiecewise(str string) bool {
+	lhs, rhs, ok := strings.Cut(str, "=")
+	if !ok || strings.TrimSpace(lhs) != "y" {
+		return false
+	}
+
+	rhs = strings.TrimSpace(rhs)
+	if strings.HasPrefix(rhs, "{") && strings.HasSuffix(rhs, "}") {
+		return true
+	}
+	_, _, ok = cutTernary(rhs)
+
+	return ok
+}
+
+func parsePiecewise(str string) (*piecewise, error) {
+	_, rhs, _ := strings.Cut(str, "=")
+	rhs = strings.TrimSpace(rhs)
+
+	// conditions and expressions, an empty condition being the default
+	var conds, exprs []string
+	if strings.HasPrefix(rhs, "{") && strings.HasSuffix(rhs, "}") {
+		for _, part := range splitTopLevel(rhs[1:len(rhs)-1], ',') {
+			cond, expr, ok := cutPiece(part)
+			if !ok {
+				cond, expr = "", part
+			}
+			conds, exprs = append(conds, cond), append(exprs, expr)
+		}
+	} else {
+		for {
+			cond, rest, ok := cutTernary(rhs)
+			if !ok {
+				conds, exprs = append(conds, ""), append(exprs, rhs)
+				break
+			}
+
+			expr, next, ok := cutPiece(rest)
+			if !ok {
+				// a ? b without an else is undefined where a is false
+				conds, exprs = append(conds, cond), append(exprs, rest)
+				break
+			}
+			conds, exprs = append(conds, cond), append(exprs, expr)
+			rhs = next
+		}
+	}
+
+	p := &piecewise{}
+	for i := range conds {
+		var pc piece
+		var err error
+		if strings.TrimSpace(conds[i]) != "" {
+			if pc.cond, err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(conds[i]), functions); err != nil {
+				return nil, err
+			}
+			p.exact = append(p.exact, conditionPoints(conds[i])...)
+		} else if i != len(conds)-1 {
+			return nil, fmt.Errorf("only the last piece can be without a condition")
+		}
+		if pc.expr, err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(exprs[i]), functions); err != nil {
+			return nil, err
+		}
+		p.pieces = append(p.pieces, pc)
+	}
+	slices.Sort(p.exact)
+
+	return p, nil
+}
+
+// cutTernary cuts s around its top level ?, if any.
+func cutTernary(s string) (cond, rest string, ok bool) {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				return s[:i], s[i+1:], true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// cutPiece cuts s around the first top level : that doesn't belong to a
+// ternary inside of it.
+func cutPiece(s string) (before, after string, ok bool) {
+	depth, ternaries := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				ternaries++
+			}
+		case ':':
+			if depth > 0 {
+				continue
+			}
+			if ternaries == 0 {
+				return s[:i], s[i+1:], true
+			}
+			ternaries--
+		}
+	}
+
+	return "", "", false
+}
+
+// conditionPoints returns the values of x compared to constants in cond,
+// such as 2 in x < 2.
+func conditionPoints(cond string) []float64 {
+	n, err := parseExpr(cond)
+	if err != nil {
+		return nil
+	}
+
+	var points []float64
+	var walk func(n *node)
+	walk = func(n *node) {
+		switch n.op {
+		case "<", "<=", ">", ">=", "==", "!=":
+			a, b := n.args[0], n.args[1]
+			if b.op == "var" && b.name == "x" {
+				a, b = b, a
+			}
+			if a.op == "var" && a.name == "x" {
+				if v, ok := constValue(b); ok {
+					points = append(points, v)
+				}
+			}
+		}
+		for _, a := range n.args {
+			walk(a)
+		}
+	}
+	walk(n)
+
+	return points
+}
+
+// constValue evaluates n if it is made of numbers and constants only.
+func constValue(n *node) (float64, bool) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		var ok bool
+		if args[i], ok = constValue(a); !ok {
+			return 0, false
+		}
+	}
+
+	switch n.op {
+	case "num":
+		return n.value, true
+	case "var":
+		switch n.name {
+		case "π", "pi":
+			return math.Pi, true
+		case "e":
+			return math.E, true
+		}
+	case "neg":
+		return -args[0], true
+	case "+":
+		return args[0] + args[1], true
+	case "-":
+		return args[0] - args[1], true
+	case "*":
+		return args[0] * args[1], true
+	case "/":
+		return args[0] / args[1], true
+	case "^":
+		return math.Pow(args[0], args[1]), true
+	}
+
+	return 0, false
+}
+
+// branch returns the index of the piece defining y at x, or -1.
+func (p *piecewise) branch(x float64) int {
+	for i, pc := range p.pieces {
+		if pc.cond == nil {
+			return i
+		}
+		if v, err := pc.cond.Eval(graphParams{x, 0}); err == nil && v == true {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (p *piecewise) value(b int, x float64) float64 {
+	if b < 0 {
+		return math.NaN()
+	}
+
+	return evalFloat(p.pieces[b].expr, graphParams{x, 0})
+}
+
+func (p *piecewise) draw(c color.Color) {
+	left, _ := fromPixel(0, 0)
+	right, _ := fromPixel(float64(graph.Rect.Dx()), 0)
+
+	// a sample every pixel, and at every exact point
+	var xs []float64
+	for px := 0; px <= graph.Rect.Dx(); px++ {
+		x, _ := fromPixel(float64(px), 0)
+		xs = append(xs, x)
+	}
+	for _, x := range p.exact {
+		if x > left && x < right {
+			xs = append(xs, x)
+		}
+	}
+	slices.Sort(xs)
+	xs = slices.Compact(xs)
+
+	line := func(b int, x0, x1 float64) {
+		px0, py0 := toPixel(x0, p.value(b, x0))
+		px1, py1 := toPixel(x1, p.value(b, x1))
+		// a jump within a piece, such as tan at π/2, isn't joined either
+		if !math.IsNaN(py0) && !math.IsNaN(py1) && math.Abs(py1-py0) < float64(graph.Rect.Dy()) {
+			drawLine(px0, py0, px1, py1, c)
+		}
+	}
+
+	type endpoint struct {
+		x, y   float64
+		closed bool
+	}
+	var endpoints []endpoint
+
+	branches := make([]int, len(xs))
+	for i, x := range xs {
+		branches[i] = p.branch(x)
+	}
+
+	for i := 1; i < len(xs); i++ {
+		x0, x1, b0, b1 := xs[i-1], xs[i], branches[i-1], branches[i]
+		if b0 == b1 {
+			if b0 >= 0 {
+				line(b0, x0, x1)
+			}
+			continue
+		}
+
+		// the boundary is either an exact point or found by bisection
+		var xb float64
+		switch {
+		case slices.Contains(p.exact, x0):
+			xb = x0
+		case slices.Contains(p.exact, x1):
+			xb = x1
+		default:
+			lo, hi := x0, x1
+			for range 60 {
+				mid := (lo + hi) / 2
+				if p.branch(mid) == b0 {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			xb = hi
+		}
+		at := p.branch(xb)
+
+		// the pieces on either side, up to the boundary
+		var ends []endpoint
+		for _, b := range []int{b0, b1} {
+			if b < 0 {
+				continue
+			}
+			if b == b0 && xb != x0 {
+				line(b, x0, xb)
+			}
+			if b == b1 && xb != x1 {
+				line(b, xb, x1)
+			}
+			ends = append(ends, endpoint{xb, p.value(b, xb), b == at})
+		}
+
+		// no markers where the pieces join
+		if len(ends) == 2 && b0 >= 0 && b1 >= 0 && (at == b0 || at == b1) {
+			_, py0 := toPixel(xb, ends[0].y)
+			_, py1 := toPixel(xb, ends[1].y)
+			if math.Abs(py0-py1) < 1 {
+				continue
+			}
+		}
+
+		// the boundary belongs to neither side, an isolated point
+		if at >= 0 && at != b0 && at != b1 {
+			ends = append(ends, endpoint{xb, p.value(at, xb), true})
+		}
+		endpoints = append(endpoints, ends...)
+	}
+
+	// open markers first, so that a closed one at the same point wins
+	slices.SortStableFunc(endpoints, func(a, b endpoint) int {
+		if a.closed == b.closed {
+			return 0
+		}
+		if b.closed {
+			return -1
+		}
+		return 1
+	})
+	for _, e := range endpoints {
+		px, py := toPixel(e.x, e.y)
+		if math.IsNaN(py) || math.IsInf(py, 0) {
+			continue
+		}
+		if e.closed {
+			drawMarker(px, py, c)
+		} else {
+			drawOpenMarker(px, py, c)
+		}
+	}
+}
+
+// drawOpenMarker draws a ring. Its inside is left alone, as clearing it
+// would also erase the other plots under it.
+func drawOpenMarker(x, y float64, c color.Color) {
+	for dx := -4; dx <= 4; dx++ {
+		for dy := -4; dy <= 4; dy++ {
+			if d := dx*dx + dy*dy; d > 6 && d <= 20 {
+				graph.Set(int(x)+dx, int(y)+dy, c)
+			}
+		}
+	}
+}
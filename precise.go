@@ -0,0 +1,752 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// bigPrecision is the number of mantissa bits of the precise evaluation,
+// used once the view is too small for float64.
+var bigPrecision uint = 256
+
+// preciseView reports whether the view spans too little, relative to its
+// distance from the origin, for float64 to tell its pixels apart.
+func preciseView() bool {
+	span := float64(graph.Rect.Dx()) / scale
+	return span < 1e-12*math.Max(math.Abs(centerX), math.Abs(centerY))
+}
+
+// bigFunc evaluates an expression at x, y with big.Float. nil stands for
+// NaN, which big.Float doesn't have.
+type bigFunc func(x, y *big.Float) *big.Float
+
+// compileBig compiles n for the precise evaluation at prec bits. The
+// elementary functions are computed at that precision; the others, such
+// as the statistical ones, are computed with float64 and converted.
+func compileBig(n *node, prec uint) (bigFunc, error) {
+	args := make([]bigFunc, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], err = compileBig(a, prec); err != nil {
+			return nil, err
+		}
+	}
+
+	constant := func(v *big.Float) (bigFunc, error) {
+		return func(x, y *big.Float) *big.Float { return v }, nil
+	}
+
+	switch n.op {
+	case "num":
+		// parsed again from the source so that 0.1 is as precise as the rest
+		v, _, err := big.ParseFloat(n.name, 10, prec, big.ToNearestEven)
+		if err != nil {
+			v = big.NewFloat(n.value).SetPrec(prec)
+		}
+		return constant(v)
+	case "var":
+		switch n.name {
+		case "x":
+			return func(x, y *big.Float) *big.Float { return x }, nil
+		case "y":
+			return func(x, y *big.Float) *big.Float { return y }, nil
+		case "π", "pi":
+			return constant(bigPi(prec))
+		case "e":
+			return constant(bigExp(newBig(prec).SetInt64(1), prec))
+		}
+
+		return nil, errors.New("unknown variable '" + n.name + "'")
+	case "neg":
+		return unaryBig(args[0], prec, func(a, r *big.Float) *big.Float { return r.Neg(a) }), nil
+	case "!":
+		return unaryBig(args[0], prec, func(a, r *big.Float) *big.Float { return boolBig(a.Sign() == 0, prec) }), nil
+	case "?":
+		c, a, b := args[0], args[1], args[2]
+		return func(x, y *big.Float) *big.Float {
+			v := c(x, y)
+			if v == nil {
+				return nil
+			}
+			if v.Sign() != 0 {
+				return a(x, y)
+			}
+			return b(x, y)
+		}, nil
+	case "call":
+		if f, ok := bigFunctions[n.name]; ok && len(args) == 1 {
+			return unaryBig(args[0], prec, func(a, r *big.Float) *big.Float { return f(a, prec) }), nil
+		}
+		if f, ok := big2Functions[n.name]; ok && len(args) == 2 {
+			return binaryBig(args[0], args[1], prec, func(a, b, r *big.Float) *big.Float { return f(a, b, prec) }), nil
+		}
+		if f, ok := functions[n.name]; ok {
+			return func(x, y *big.Float) *big.Float {
+				values := make([]interface{}, len(args))
+				for i, a := range args {
+					v := a(x, y)
+					if v == nil {
+						return nil
+					}
+					values[i], _ = v.Float64()
+				}
+				v, err := f(values...)
+				r, ok := v.(float64)
+				if err != nil || !ok || math.IsNaN(r) {
+					return nil
+				}
+				return newBig(prec).SetFloat64(r)
+			}, nil
+		}
+
+		return nil, errors.New("unknown function '" + n.name + "'")
+	}
+
+	op, ok := bigOperators[n.op]
+	if !ok {
+		return nil, errors.New("'" + n.op + "' isn't defined with big numbers")
+	}
+
+	return binaryBig(args[0], args[1], prec, func(a, b, r *big.Float) *big.Float { return op(a, b, prec) }), nil
+}
+
+func newBig(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func boolBig(b bool, prec uint) *big.Float {
+	return newBig(prec).SetInt64(int64(boolValue(b)))
+}
+
+// guardNaN turns the operations big.Float panics on into NaN (nil).
+func guardNaN(f func() *big.Float) (r *big.Float) {
+	defer func() {
+		if e := recover(); e != nil {
+			if _, ok := e.(big.ErrNaN); !ok {
+				panic(e)
+			}
+			r = nil
+		}
+	}()
+
+	return f()
+}
+
+func unaryBig(a bigFunc, prec uint, op func(a, r *big.Float) *big.Float) bigFunc {
+	return func(x, y *big.Float) *big.Float {
+		v := a(x, y)
+		if v == nil {
+			return nil
+		}
+		return guardNaN(func() *big.Float { return op(v, newBig(prec)) })
+	}
+}
+
+func binaryBig(a, b bigFunc, prec uint, op func(a, b, r *big.Float) *big.Float) bigFunc {
+	return func(x, y *big.Float) *big.Float {
+		u, v := a(x, y), b(x, y)
+		if u == nil || v == nil {
+			return nil
+		}
+		return guardNaN(func() *big.Float { return op(u, v, newBig(prec)) })
+	}
+}
+
+var bigOperators = map[string]func(a, b *big.Float, prec uint) *big.Float{
+	"+": func(a, b *big.Float, prec uint) *big.Float { return newBig(prec).Add(a, b) },
+	"-": func(a, b *big.Float, prec uint) *big.Float { return newBig(prec).Sub(a, b) },
+	"*": func(a, b *big.Float, prec uint) *big.Float { return newBig(prec).Mul(a, b) },
+	"/": func(a, b *big.Float, prec uint) *big.Float {
+		if a.Sign() == 0 && b.Sign() == 0 {
+			return nil
+		}
+		return newBig(prec).Quo(a, b)
+	},
+	"%":  bigMod,
+	"^":  bigPow,
+	"<":  func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) < 0, prec) },
+	"<=": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) <= 0, prec) },
+	">":  func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) > 0, prec) },
+	">=": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) >= 0, prec) },
+	"==": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) == 0, prec) },
+	"!=": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Cmp(b) != 0, prec) },
+	"&&": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Sign() != 0 && b.Sign() != 0, prec) },
+	"||": func(a, b *big.Float, prec uint) *big.Float { return boolBig(a.Sign() != 0 || b.Sign() != 0, prec) },
+}
+
+var bigFunctions = map[string]func(x *big.Float, prec uint) *big.Float{
+	"sqrt": func(x *big.Float, prec uint) *big.Float {
+		if x.Sign() < 0 {
+			return nil
+		}
+		return newBig(prec).Sqrt(x)
+	},
+	"cbrt": func(x *big.Float, prec uint) *big.Float {
+		if x.Sign() == 0 {
+			return newBig(prec)
+		}
+		r := bigExp(newBig(prec+32).Quo(bigLog(newBig(prec).Abs(x), prec+32), big.NewFloat(3)), prec)
+		if x.Sign() < 0 {
+			r.Neg(r)
+		}
+		return r
+	},
+	"abs":   func(x *big.Float, prec uint) *big.Float { return newBig(prec).Abs(x) },
+	"floor": bigFloor,
+	"ceil": func(x *big.Float, prec uint) *big.Float {
+		r := bigFloor(newBig(prec).Neg(x), prec)
+		return r.Neg(r)
+	},
+	"exp":   bigExp,
+	"log":   bigLog,
+	"log2":  func(x *big.Float, prec uint) *big.Float { return logBase(x, 2, prec) },
+	"log10": func(x *big.Float, prec uint) *big.Float { return logBase(x, 10, prec) },
+	"sin": func(x *big.Float, prec uint) *big.Float {
+		s, _ := bigSinCos(x, prec)
+		return s
+	},
+	"cos": func(x *big.Float, prec uint) *big.Float {
+		_, c := bigSinCos(x, prec)
+		return c
+	},
+	"tan": func(x *big.Float, prec uint) *big.Float {
+		s, c := bigSinCos(x, prec)
+		if s == nil {
+			return nil
+		}
+		return newBig(prec).Quo(s, c)
+	},
+	"atan": bigAtan,
+	"asin": bigAsin,
+	"acos": func(x *big.Float, prec uint) *big.Float {
+		a := bigAsin(x, prec)
+		if a == nil {
+			return nil
+		}
+		half := newBig(prec).SetMantExp(bigPi(prec), -1)
+		return half.Sub(half, a)
+	},
+	"sinh": func(x *big.Float, prec uint) *big.Float {
+		// the Taylor series avoids the cancellation of e^x - e^-x near 0
+		if x.MantExp(nil) <= 0 {
+			return taylor(x, 1, 1, prec)
+		}
+		w := prec + 32
+		ex := bigExp(x, w)
+		r := newBig(w).Quo(big.NewFloat(1), ex)
+		r.Sub(ex, r)
+		return newBig(prec).SetMantExp(r, -1)
+	},
+	"cosh": func(x *big.Float, prec uint) *big.Float {
+		w := prec + 32
+		ex := bigExp(x, w)
+		r := newBig(w).Quo(big.NewFloat(1), ex)
+		r.Add(ex, r)
+		return newBig(prec).SetMantExp(r, -1)
+	},
+	"tanh": func(x *big.Float, prec uint) *big.Float {
+		// 1 - 2/(e^2x + 1), or its opposite for x < 0 so that e^2x
+		// doesn't overflow
+		w := prec + 32
+		a := newBig(w).Abs(x)
+		e2 := bigExp(a.Add(a, a), w)
+		r := newBig(w).Quo(big.NewFloat(2), e2.Add(e2, big.NewFloat(1)))
+		r.Sub(big.NewFloat(1), r)
+		if x.Sign() < 0 {
+			r.Neg(r)
+		}
+		return newBig(prec).Set(r)
+	},
+	"asinh": func(x *big.Float, prec uint) *big.Float {
+		// log(|x| + sqrt(x² + 1)) with the sign of x
+		w := prec + 32
+		a := newBig(w).Abs(x)
+		r := newBig(w).Mul(a, a)
+		r.Sqrt(r.Add(r, big.NewFloat(1)))
+		r = bigLog(r.Add(r, a), w)
+		if x.Sign() < 0 {
+			r.Neg(r)
+		}
+		return newBig(prec).Set(r)
+	},
+	"acosh": func(x *big.Float, prec uint) *big.Float {
+		if x.Cmp(big.NewFloat(1)) < 0 {
+			return nil
+		}
+		w := prec + 32
+		r := newBig(w).Mul(x, x)
+		r.Sqrt(r.Sub(r, big.NewFloat(1)))
+		return newBig(prec).Set(bigLog(r.Add(r, x), w))
+	},
+	"atanh": func(x *big.Float, prec uint) *big.Float {
+		if newBig(prec).Abs(x).Cmp(big.NewFloat(1)) >= 0 {
+			return nil
+		}
+		w := prec + 32
+		num := newBig(w).Add(big.NewFloat(1), x)
+		den := newBig(w).Sub(big.NewFloat(1), x)
+		r := bigLog(num.Quo(num, den), w)
+		return newBig(prec).SetMantExp(r, -1)
+	},
+}
+
+var big2Functions = map[string]func(a, b *big.Float, prec uint) *big.Float{
+	"min": func(a, b *big.Float, prec uint) *big.Float {
+		if a.Cmp(b) <= 0 {
+			return a
+		}
+		return b
+	},
+	"max": func(a, b *big.Float, prec uint) *big.Float {
+		if a.Cmp(b) >= 0 {
+			return a
+		}
+		return b
+	},
+	"hypot": func(a, b *big.Float, prec uint) *big.Float {
+		r := newBig(prec+32).Mul(a, a)
+		r.Add(r, newBig(prec+32).Mul(b, b))
+		return newBig(prec).Sqrt(r)
+	},
+	"atan2": bigAtan2,
+	"mod":   bigMod,
+	"copysign": func(a, b *big.Float, prec uint) *big.Float {
+		r := newBig(prec).Abs(a)
+		if b.Signbit() {
+			r.Neg(r)
+		}
+		return r
+	},
+	"dim": func(a, b *big.Float, prec uint) *big.Float {
+		r := newBig(prec).Sub(a, b)
+		if r.Sign() < 0 {
+			r.SetInt64(0)
+		}
+		return r
+	},
+}
+
+// bigConstants caches π and ln 2 by precision.
+var bigConstants sync.Map
+
+type bigConstant struct {
+	name string
+	prec uint
+}
+
+func cachedConstant(name string, prec uint, compute func(w uint) *big.Float) *big.Float {
+	key := bigConstant{name, prec}
+	if v, ok := bigConstants.Load(key); ok {
+		return v.(*big.Float)
+	}
+
+	v := newBig(prec).Set(compute(prec + 32))
+	bigConstants.Store(key, v)
+
+	return v
+}
+
+// bigPi computes π by Machin's formula, 16·atan(1/5) - 4·atan(1/239).
+func bigPi(prec uint) *big.Float {
+	return cachedConstant("π", prec, func(w uint) *big.Float {
+		a := atanInverse(5, w)
+		b := atanInverse(239, w)
+		a.Mul(a, big.NewFloat(16))
+		b.Mul(b, big.NewFloat(4))
+		return a.Sub(a, b)
+	})
+}
+
+// atanInverse computes atan(1/n) with its Taylor series.
+func atanInverse(n int64, w uint) *big.Float {
+	x := newBig(w).Quo(big.NewFloat(1), newBig(w).SetInt64(n))
+	return taylor(x, -1, 2, w)
+}
+
+// bigLn2 computes ln 2 = 2·atanh(1/3).
+func bigLn2(prec uint) *big.Float {
+	return cachedConstant("ln2", prec, func(w uint) *big.Float {
+		x := newBig(w).Quo(big.NewFloat(1), big.NewFloat(3))
+		r := taylor(x, 1, 2, w)
+		return r.Add(r, r)
+	})
+}
+
+// taylor sums x + sign·x³/d₃ + x⁵/d₅ + …, sign alternating the terms when
+// -1, where dₙ is n for kind 2 (atan and atanh) or n! for kind 1 (sin and
+// sinh).
+func taylor(x *big.Float, sign float64, kind int, w uint) *big.Float {
+	sum := newBig(w).Set(x)
+	power := newBig(w).Set(x)
+	x2 := newBig(w).Mul(x, x)
+	if sign < 0 {
+		x2.Neg(x2)
+	}
+
+	factorial := newBig(w).SetInt64(1)
+	term := newBig(w)
+	for n := int64(3); ; n += 2 {
+		power.Mul(power, x2)
+		if kind == 1 {
+			factorial.Mul(factorial, newBig(w).SetInt64((n-1)*n))
+			term.Quo(power, factorial)
+		} else {
+			term.Quo(power, newBig(w).SetInt64(n))
+		}
+		if term.Sign() == 0 || term.MantExp(nil) < sum.MantExp(nil)-int(w) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// bigExp computes e^x by reducing x to r = x - k·ln 2 and then to r/2⁸,
+// whose series converges quickly, squaring the result back.
+func bigExp(x *big.Float, prec uint) *big.Float {
+	if x.IsInf() {
+		if x.Sign() > 0 {
+			return newBig(prec).SetInf(false)
+		}
+		return newBig(prec)
+	}
+	if f, _ := x.Float64(); math.Abs(f) > 1e9 {
+		if f > 0 {
+			return newBig(prec).SetInf(false)
+		}
+		return newBig(prec)
+	}
+
+	w := prec + 32 + uint(max(0, x.MantExp(nil)))
+	ln2 := bigLn2(w)
+	k, _ := newBig(w).Quo(x, ln2).Int64()
+	r := newBig(w).Mul(newBig(w).SetInt64(k), ln2)
+	r.Sub(x, r)
+	r.SetMantExp(r, -8)
+
+	sum := newBig(w).SetInt64(1)
+	term := newBig(w).SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, newBig(w).SetInt64(n))
+		if term.Sign() == 0 || term.MantExp(nil) < -int(w) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for range 8 {
+		sum.Mul(sum, sum)
+	}
+
+	return newBig(prec).SetMantExp(sum, int(k))
+}
+
+// bigLog computes log x from x = m·2ᵉ, with m near 1, as
+// 2·atanh((m-1)/(m+1)) + e·ln 2.
+func bigLog(x *big.Float, prec uint) *big.Float {
+	switch {
+	case x.Sign() < 0:
+		return nil
+	case x.Sign() == 0:
+		return newBig(prec).SetInf(true)
+	case x.IsInf():
+		return newBig(prec).SetInf(false)
+	}
+
+	w := prec + 32
+	m := newBig(w)
+	e := x.MantExp(m)
+	if m.Cmp(big.NewFloat(math.Sqrt2/2)) < 0 {
+		m.SetMantExp(m, 1)
+		e--
+	}
+
+	z := newBig(w).Sub(m, big.NewFloat(1))
+	z.Quo(z, newBig(w).Add(m, big.NewFloat(1)))
+	r := taylor(z, 1, 2, w)
+	r.Add(r, r)
+
+	ln2 := newBig(w).Mul(bigLn2(w), newBig(w).SetInt64(int64(e)))
+
+	return newBig(prec).Add(r, ln2)
+}
+
+func logBase(x *big.Float, base int64, prec uint) *big.Float {
+	l := bigLog(x, prec+32)
+	if l == nil {
+		return nil
+	}
+
+	return newBig(prec).Quo(l, bigLog(newBig(prec+32).SetInt64(base), prec+32))
+}
+
+// bigSinCos computes sin x and cos x by reducing x to [-π, π] and summing
+// their series.
+func bigSinCos(x *big.Float, prec uint) (sin, cos *big.Float) {
+	if x.IsInf() {
+		return nil, nil
+	}
+
+	w := prec + 32 + uint(max(0, x.MantExp(nil)))
+	twoPi := newBig(w).SetMantExp(bigPi(w), 1)
+	k := newBig(w).Quo(x, twoPi)
+	k.Add(k, big.NewFloat(0.5))
+	k = bigFloor(k, w)
+	r := newBig(w).Mul(k, twoPi)
+	r.Sub(x, r)
+
+	sin = taylor(r, -1, 1, w)
+
+	// cos r = 1 - r²/2! + r⁴/4! - …
+	cos = newBig(w).SetInt64(1)
+	term := newBig(w).SetInt64(1)
+	r2 := newBig(w).Mul(r, r)
+	r2.Neg(r2)
+	for n := int64(2); ; n += 2 {
+		term.Mul(term, r2)
+		term.Quo(term, newBig(w).SetInt64((n-1)*n))
+		if term.Sign() == 0 || term.MantExp(nil) < -int(w) {
+			break
+		}
+		cos.Add(cos, term)
+	}
+
+	return newBig(prec).Set(sin), newBig(prec).Set(cos)
+}
+
+// bigAtan computes atan x, using atan x = π/2 - atan(1/x) beyond 1 and
+// halving the angle three times with atan x = 2·atan(x/(1 + √(1 + x²)))
+// before summing the series.
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	w := prec + 32
+	a := newBig(w).Abs(x)
+
+	inverted := a.Cmp(big.NewFloat(1)) > 0
+	if inverted {
+		if a.IsInf() {
+			a.SetInt64(0)
+		} else {
+			a.Quo(big.NewFloat(1), a)
+		}
+	}
+
+	for range 3 {
+		s := newBig(w).Mul(a, a)
+		s.Sqrt(s.Add(s, big.NewFloat(1)))
+		a.Quo(a, s.Add(s, big.NewFloat(1)))
+	}
+	r := taylor(a, -1, 2, w)
+	r.SetMantExp(r, 3)
+
+	if inverted {
+		r.Sub(newBig(w).SetMantExp(bigPi(w), -1), r)
+	}
+	if x.Sign() < 0 {
+		r.Neg(r)
+	}
+
+	return newBig(prec).Set(r)
+}
+
+func bigAsin(x *big.Float, prec uint) *big.Float {
+	w := prec + 32
+	c := newBig(w).Mul(x, x)
+	c.Sub(big.NewFloat(1), c)
+	switch c.Sign() {
+	case -1:
+		return nil
+	case 0:
+		r := newBig(prec).SetMantExp(bigPi(prec), -1)
+		if x.Sign() < 0 {
+			r.Neg(r)
+		}
+		return r
+	}
+
+	c.Sqrt(c)
+
+	return newBig(prec).Set(bigAtan(c.Quo(x, c), w))
+}
+
+func bigAtan2(y, x *big.Float, prec uint) *big.Float {
+	w := prec + 32
+	if x.Sign() == 0 {
+		if y.Sign() == 0 {
+			return newBig(prec)
+		}
+		r := newBig(prec).SetMantExp(bigPi(prec), -1)
+		if y.Sign() < 0 {
+			r.Neg(r)
+		}
+		return r
+	}
+
+	r := bigAtan(newBig(w).Quo(y, x), w)
+	if x.Sign() < 0 {
+		if y.Signbit() {
+			r.Sub(r, bigPi(w))
+		} else {
+			r.Add(r, bigPi(w))
+		}
+	}
+
+	return newBig(prec).Set(r)
+}
+
+func bigFloor(x *big.Float, prec uint) *big.Float {
+	if x.IsInf() {
+		return newBig(prec).Set(x)
+	}
+
+	i, _ := x.Int(nil)
+	r := newBig(prec).SetInt(i)
+	if r.Cmp(x) > 0 {
+		r.Sub(r, big.NewFloat(1))
+	}
+
+	return r
+}
+
+// bigMod is a - b·trunc(a/b), as math.Mod.
+func bigMod(a, b *big.Float, prec uint) *big.Float {
+	if b.Sign() == 0 || a.IsInf() {
+		return nil
+	}
+
+	w := prec + 32 + uint(max(0, a.MantExp(nil)-b.MantExp(nil)))
+	q := newBig(w).Quo(a, b)
+	i, _ := q.Int(nil)
+	r := newBig(w).Mul(b, newBig(w).SetInt(i))
+
+	return newBig(prec).Sub(a, r)
+}
+
+// bigPow computes integer powers exactly by squaring, and the others as
+// e^(b·log a).
+func bigPow(a, b *big.Float, prec uint) *big.Float {
+	if b.IsInt() && !b.IsInf() {
+		if n, acc := b.Int64(); acc == big.Exact && n > -1<<31 && n < 1<<31 {
+			w := prec + 32 + uint(bitLength(n))
+			r := newBig(w).SetInt64(1)
+			p := newBig(w).Set(a)
+			for k := n; k != 0; k /= 2 {
+				if k%2 != 0 {
+					r.Mul(r, p)
+				}
+				p.Mul(p, p)
+			}
+			if n < 0 {
+				if r.Sign() == 0 {
+					return newBig(prec).SetInf(false)
+				}
+				r.Quo(big.NewFloat(1), r)
+			}
+			return newBig(prec).Set(r)
+		}
+	}
+
+	switch a.Sign() {
+	case -1:
+		return nil
+	case 0:
+		if b.Sign() > 0 {
+			return newBig(prec)
+		}
+		return newBig(prec).SetInf(false)
+	}
+
+	w := prec + 32
+	l := bigLog(a, w)
+
+	return bigExp(l.Mul(l, b), prec)
+}
+
+func bitLength(n int64) int {
+	l := 0
+	for n != 0 {
+		n /= 2
+		l++
+	}
+
+	return l
+}
+
+// preciseGraph is parseMultiequationGraph with big.Float, drawn across the
+// pixels of the view in place of the float64 samples.
+type preciseGraph struct {
+	fs [2][]bigFunc
+
+	// vertical samples the rows of the view for x = f(y), rather than its
+	// columns.
+	vertical bool
+}
+
+// parsePreciseGraph parses str as parseMultiequationGraph does, failing
+// for the expressions compileBig doesn't handle, such as data columns.
+func parsePreciseGraph(str string) (*preciseGraph, error) {
+	s := parseMultiequation(str)
+
+	g := &preciseGraph{vertical: len(s[1]) == 1 && strings.TrimSpace(s[1][0]) == "y"}
+	for i := range s {
+		for _, eq := range s[i] {
+			n, err := parseExpr(eq)
+			if err != nil {
+				return nil, err
+			}
+			f, err := compileBig(n, bigPrecision)
+			if err != nil {
+				return nil, err
+			}
+			g.fs[i] = append(g.fs[i], f)
+		}
+	}
+
+	return g, nil
+}
+
+func (g *preciseGraph) draw(c color.Color) {
+	w, h := float64(graph.Rect.Dx()), float64(graph.Rect.Dy())
+	prec := bigPrecision
+
+	// toPixel, subtracting the center at full precision
+	pixel := func(x, y *big.Float) (float64, float64) {
+		dx := newBig(prec).Sub(x, center[0])
+		dy := newBig(prec).Sub(y, center[1])
+		fx, _ := dx.Float64()
+		fy, _ := dy.Float64()
+		return w/2 + fx*scale, h/2 - fy*scale
+	}
+
+	n := w
+	if g.vertical {
+		n = h
+	}
+	for i := 0.0; i < 2*n; i++ {
+		// half pixel steps from the left, or the bottom, of the view
+		offset := (i/2 - n/2) / scale
+		x := newBig(prec).Add(center[0], big.NewFloat(offset))
+		y := newBig(prec).Add(center[1], big.NewFloat(offset))
+
+		var xs, ys []*big.Float
+		for _, f := range g.fs[0] {
+			if v := f(x, y); v != nil {
+				xs = append(xs, v)
+			}
+		}
+		for _, f := range g.fs[1] {
+			if v := f(x, y); v != nil {
+				ys = append(ys, v)
+			}
+		}
+
+		for _, x1 := range xs {
+			for _, y1 := range ys {
+				px, py := pixel(x1, y1)
+				setpix(px, py, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// evalBig compiles s at prec bits and evaluates it at x, y = 0.
+func evalBig(t *testing.T, s, x string, prec uint) *big.Float {
+	t.Helper()
+
+	n, err := parseExpr(s)
+	if err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	f, err := compileBig(n, prec)
+	if err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	bx, _, err := big.ParseFloat(x, 10, prec, big.ToNearestEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f(bx, newBig(prec))
+}
+
+// TestCompileBigDigits checks the elementary functions at 256 bits, about
+// 77 digits, against their first 78 digits.
+func TestCompileBigDigits(t *testing.T) {
+	tests := []struct {
+		expr, x, want string
+	}{
+		{"pi", "0", "3.141592653589793238462643383279502884197169399375105820974944592307816406286208"},
+		{"e", "0", "2.718281828459045235360287471352662497757247093699959574966967627724076630353547"},
+		{"exp(x)", "1", "2.718281828459045235360287471352662497757247093699959574966967627724076630353547"},
+		{"exp(x)", "0.5", "1.648721270700128146848650787814163571653776100710148011575079311640661021194215"},
+		{"log(x)", "2", "0.693147180559945309417232121458176568075500134360255254120680009493393621969694"},
+		{"log(x)", "10", "2.302585092994045684017991454684364207601101488628772976033327900967572609677352"},
+		{"sin(x)", "1", "0.841470984807896506652502321630298999622563060798371065672751709991910404391239"},
+		{"cos(x)", "1", "0.540302305868139717400936607442976603732310420617922227670097255381100394774471"},
+		{"atan(x)", "1", "0.785398163397448309615660845819875721049292349843776455243736148076954101571552"},
+	}
+
+	const prec = 256
+	for _, tt := range tests {
+		got := evalBig(t, tt.expr, tt.x, prec)
+		if got == nil {
+			t.Errorf("%s at %s is undefined", tt.expr, tt.x)
+			continue
+		}
+
+		want, _, _ := big.ParseFloat(tt.want, 10, prec, big.ToNearestEven)
+		diff := newBig(prec).Sub(got, want)
+		if diff.Abs(diff).Cmp(big.NewFloat(1e-74)) > 0 {
+			t.Errorf("%s at %s = %s, want %s", tt.expr, tt.x, got.Text('g', 78), tt.want)
+		}
+	}
+}
+
+// TestCompileBigFloat64 checks that at ordinary scales the precise
+// evaluation agrees with float64.
+func TestCompileBigFloat64(t *testing.T) {
+	tests := []struct {
+		expr string
+		f    func(x float64) float64
+	}{
+		{"sin(x)*exp(-x/3)", func(x float64) float64 { return math.Sin(x) * math.Exp(-x/3) }},
+		{"log(x*x+1)-cos(2*x)", func(x float64) float64 { return math.Log(x*x+1) - math.Cos(2*x) }},
+		{"atan(x)/(1+x^2)", func(x float64) float64 { return math.Atan(x) / (1 + x*x) }},
+		{"sqrt(abs(x))+tan(x/4)", func(x float64) float64 { return math.Sqrt(math.Abs(x)) + math.Tan(x/4) }},
+		{"2^x-x^3", func(x float64) float64 { return math.Pow(2, x) - x*x*x }},
+	}
+
+	for _, tt := range tests {
+		for _, x := range []string{"-3.7", "-0.25", "0.1", "1", "2.5", "9.75"} {
+			got := evalBig(t, tt.expr, x, bigPrecision)
+			if got == nil {
+				t.Errorf("%s at %s is undefined", tt.expr, x)
+				continue
+			}
+
+			v, _ := got.Float64()
+			fx, _ := new(big.Float).SetPrec(bigPrecision).SetString(x)
+			xf, _ := fx.Float64()
+			want := tt.f(xf)
+			if math.Abs(v-want) > 1e-13*max(1, math.Abs(want)) {
+				t.Errorf("%s at %s = %v, want %v", tt.expr, x, v, want)
+			}
+		}
+	}
+}
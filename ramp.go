@@ -0,0 +1,300 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// gradientStop is the color of a gradient at pos, in [0, 1].
+type gradientStop struct {
+	pos   float64
+	color color.NRGBA
+}
+
+// gradient maps normalized noise values to colors, interpolating between
+// its stops in linear RGB, or in OKLab where the steps look even.
+type gradient struct {
+	stops []gradientStop
+	oklab bool
+}
+
+func stop(pos float64, v uint32) gradientStop {
+	c := hex(v)
+	return gradientStop{pos, color.NRGBA{c.R, c.G, c.B, c.A}}
+}
+
+var gradientPresets = map[string][]gradientStop{
+	"Grayscale": {stop(0, 0x000000), stop(1, 0xffffff)},
+	"Terrain": {
+		stop(0, 0x0b2a5b), stop(0.42, 0x2f6fb5), stop(0.48, 0xe8d9a0), stop(0.52, 0x5f9e3a),
+		stop(0.7, 0x2f6b2a), stop(0.82, 0x7a6a55), stop(0.92, 0xa49a8c), stop(1, 0xffffff),
+	},
+	"Heat": {stop(0, 0x000000), stop(0.35, 0x9b0000), stop(0.65, 0xff7a00), stop(0.85, 0xffe14a), stop(1, 0xffffff)},
+}
+
+var gradientPresetNames = []string{"Grayscale", "Terrain", "Heat", "Viridis", "Magma", "Cividis", "Diverging"}
+
+func init() {
+	// the colormaps of the heatmaps, as evenly spaced stops
+	for name, c := range colormaps {
+		var stops []gradientStop
+		for i, s := range c {
+			stops = append(stops, gradientStop{float64(i) / float64(len(c)-1), color.NRGBA{s.R, s.G, s.B, s.A}})
+		}
+		gradientPresets[name] = stops
+	}
+}
+
+// srgbToLinear and linearToSRGB convert between the sRGB transfer curve
+// and linear light, in [0, 1].
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	v = max(0, min(1, v))
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// toOKLab and fromOKLab convert linear RGB to Björn Ottosson's OKLab.
+func toOKLab(r, g, b float64) (l, a, bb float64) {
+	lms := [3]float64{
+		0.4122214708*r + 0.5363325363*g + 0.0514459929*b,
+		0.2119034982*r + 0.6806995451*g + 0.1073969566*b,
+		0.0883024619*r + 0.2817188376*g + 0.6299787005*b,
+	}
+	for i := range lms {
+		lms[i] = math.Cbrt(lms[i])
+	}
+
+	return 0.2104542553*lms[0] + 0.7936177850*lms[1] - 0.0040720468*lms[2],
+		1.9779984951*lms[0] - 2.4285922050*lms[1] + 0.4505937099*lms[2],
+		0.0259040371*lms[0] + 0.7827717662*lms[1] - 0.8086757660*lms[2]
+}
+
+func fromOKLab(l, a, b float64) (r, g, bb float64) {
+	lms := [3]float64{
+		l + 0.3963377774*a + 0.2158037573*b,
+		l - 0.1055613458*a - 0.0638541728*b,
+		l - 0.0894841775*a - 1.2914855480*b,
+	}
+	for i := range lms {
+		lms[i] = lms[i] * lms[i] * lms[i]
+	}
+
+	return 4.0767416621*lms[0] - 3.3077115913*lms[1] + 0.2309699292*lms[2],
+		-1.2684380046*lms[0] + 2.6097574011*lms[1] - 0.3413193965*lms[2],
+		-0.0041960863*lms[0] - 0.7034186147*lms[1] + 1.7076147010*lms[2]
+}
+
+// at returns the color at t, in [0, 1].
+func (g gradient) at(t float64) color.NRGBA64 {
+	if len(g.stops) == 0 || math.IsNaN(t) {
+		return color.NRGBA64{}
+	}
+
+	t = max(0, min(1, t))
+	i, _ := slices.BinarySearchFunc(g.stops, t, func(s gradientStop, t float64) int {
+		switch {
+		case s.pos < t:
+			return -1
+		case s.pos > t:
+			return 1
+		}
+		return 0
+	})
+	if i == 0 {
+		return nrgba64(g.stops[0].color)
+	}
+	if i == len(g.stops) {
+		return nrgba64(g.stops[len(g.stops)-1].color)
+	}
+
+	a, b := g.stops[i-1], g.stops[i]
+	f := 0.0
+	if b.pos > a.pos {
+		f = (t - a.pos) / (b.pos - a.pos)
+	}
+
+	lin := func(c color.NRGBA) [3]float64 {
+		return [3]float64{srgbToLinear(float64(c.R) / 255), srgbToLinear(float64(c.G) / 255), srgbToLinear(float64(c.B) / 255)}
+	}
+	ca, cb := lin(a.color), lin(b.color)
+	if g.oklab {
+		ca[0], ca[1], ca[2] = toOKLab(ca[0], ca[1], ca[2])
+		cb[0], cb[1], cb[2] = toOKLab(cb[0], cb[1], cb[2])
+	}
+
+	var c [3]float64
+	for k := range c {
+		c[k] = ca[k] + f*(cb[k]-ca[k])
+	}
+	if g.oklab {
+		c[0], c[1], c[2] = fromOKLab(c[0], c[1], c[2])
+	}
+
+	alpha := float64(a.color.A) + f*(float64(b.color.A)-float64(a.color.A))
+
+	return color.NRGBA64{
+		uint16(linearToSRGB(c[0])*0xffff + 0.5),
+		uint16(linearToSRGB(c[1])*0xffff + 0.5),
+		uint16(linearToSRGB(c[2])*0xffff + 0.5),
+		uint16(alpha*0x101 + 0.5),
+	}
+}
+
+func nrgba64(c color.NRGBA) color.NRGBA64 {
+	return color.NRGBA64{uint16(c.R) * 0x101, uint16(c.G) * 0x101, uint16(c.B) * 0x101, uint16(c.A) * 0x101}
+}
+
+// table samples the gradient at n evenly spaced values, so that a render
+// looks colors up rather than interpolating them for every pixel.
+func (g gradient) table(n int) []color.NRGBA64 {
+	t := make([]color.NRGBA64, n)
+	for i := range t {
+		t[i] = g.at(float64(i) / float64(n-1))
+	}
+
+	return t
+}
+
+func (g gradient) preview(w, h int) image.Image {
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for x := range w {
+		c := g.at(float64(x) / float64(w-1))
+		for y := range h {
+			img.SetNRGBA64(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// gradientEditor edits g: a preset, the interpolation, and the stops, each
+// a swatch opening a color picker, a position and a remove button.
+func gradientEditor(g *gradient, w fyne.Window) fyne.CanvasObject {
+	bar := canvas.NewImageFromImage(g.preview(256, 1))
+	bar.ScaleMode = canvas.ImageScaleSmooth
+	bar.FillMode = canvas.ImageFillStretch
+	bar.SetMinSize(fyne.NewSize(256, 20))
+
+	stopsBox := container.NewVBox()
+
+	var showStops func()
+	changed := func() {
+		slices.SortStableFunc(g.stops, func(a, b gradientStop) int {
+			switch {
+			case a.pos < b.pos:
+				return -1
+			case a.pos > b.pos:
+				return 1
+			}
+			return 0
+		})
+		bar.Image = g.preview(256, 1)
+		bar.Refresh()
+	}
+
+	showStops = func() {
+		stopsBox.RemoveAll()
+		for i := range g.stops {
+			s := &g.stops[i]
+
+			var sw *swatch
+			sw = newSwatch(s.color, widget.NewEntry().MinSize().Height, func() {
+				picker := dialog.NewColorPicker("Color", "Gradient stop color", func(c color.Color) {
+					s.color = color.NRGBAModel.Convert(c).(color.NRGBA)
+					sw.SetColor(s.color)
+					changed()
+				}, w)
+				picker.Advanced = true
+				picker.SetColor(s.color)
+				picker.Show()
+			})
+
+			posInput := widget.NewEntry()
+			posInput.SetText(strconv.FormatFloat(s.pos, 'f', 2, 64))
+			posInput.OnSubmitted = func(v string) {
+				p, err := strconv.ParseFloat(v, 64)
+				if err != nil || p < 0 || p > 1 {
+					return
+				}
+				s.pos = p
+				changed()
+				showStops()
+			}
+
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				if len(g.stops) <= 2 {
+					return
+				}
+				g.stops = slices.Delete(g.stops, i, i+1)
+				changed()
+				showStops()
+			})
+
+			stopsBox.Add(container.NewBorder(nil, nil, sw, removeButton, posInput))
+		}
+	}
+
+	presetSelect := widget.NewSelect(gradientPresetNames, func(name string) {
+		g.stops = slices.Clone(gradientPresets[name])
+		changed()
+		showStops()
+	})
+
+	interpolationSelect := widget.NewSelect([]string{"Linear RGB", "OKLab"}, func(s string) {
+		g.oklab = s == "OKLab"
+		changed()
+	})
+	if g.oklab {
+		interpolationSelect.SetSelected("OKLab")
+	} else {
+		interpolationSelect.SetSelected("Linear RGB")
+	}
+
+	addButton := widget.NewButtonWithIcon("Stop", theme.ContentAddIcon(), func() {
+		// halfway along the widest gap
+		gap := 0
+		for i := 1; i < len(g.stops)-1; i++ {
+			if g.stops[i+1].pos-g.stops[i].pos > g.stops[gap+1].pos-g.stops[gap].pos {
+				gap = i
+			}
+		}
+		pos := 0.5
+		if len(g.stops) >= 2 {
+			pos = (g.stops[gap].pos + g.stops[gap+1].pos) / 2
+		}
+		c := g.at(pos)
+		g.stops = append(g.stops, gradientStop{pos, color.NRGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), uint8(c.A >> 8)}})
+		changed()
+		showStops()
+	})
+
+	changed()
+	showStops()
+
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabel("Preset"), presetSelect, widget.NewLabel("Interpolation"), interpolationSelect, addButton),
+		bar,
+		stopsBox,
+	)
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// memo caches up to limit values, forgetting the oldest first so that
+// memory stays bounded however long Qraph runs.
+type memo[K comparable, V any] struct {
+	limit  int
+	values map[K]V
+
+	// keys is a ring of the cached keys in the order they were added, next
+	// being the oldest once the ring is full.
+	keys []K
+	next int
+}
+
+func newMemo[K comparable, V any](limit int) *memo[K, V] {
+	return &memo[K, V]{limit: limit, values: make(map[K]V)}
+}
+
+func (m *memo[K, V]) get(k K) (V, bool) {
+	v, ok := m.values[k]
+	return v, ok
+}
+
+func (m *memo[K, V]) put(k K, v V) {
+	if _, ok := m.values[k]; ok {
+		m.values[k] = v
+		return
+	}
+
+	if len(m.keys) < m.limit {
+		m.keys = append(m.keys, k)
+	} else {
+		delete(m.values, m.keys[m.next])
+		m.keys[m.next] = k
+		m.next = (m.next + 1) % m.limit
+	}
+	m.values[k] = v
+}
+
+// maxTerms bounds the number of terms of a sum, a product or a recurrence,
+// beyond which they evaluate to NaN rather than hang the render.
+const maxTerms = 1_000_000
+
+// series is the body of a summation(expr, k, a, b) or product(expr, k, a, b).
+type series struct {
+	body  *govaluate.EvaluableExpression
+	index string
+
+	// free are the other variables of the body, passed along with the
+	// bounds since govaluate functions don't see the parameters.
+	free []string
+}
+
+// seriesBodies caches the series found by rewriteSeries by their key, the
+// index and the body, which the rewritten expressions pass along so that a
+// series forgotten is compiled again.
+var seriesBodies = newMemo[string, *series](1024)
+
+// seriesKeyEscaper quotes a series key as a govaluate string.
+var seriesKeyEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// boundParams resolves the variables bound by a series, and the constants
+// and data columns as graphParams does.
+type boundParams map[string]interface{}
+
+func (p boundParams) Get(name string) (interface{}, error) {
+	if v, ok := p[name]; ok {
+		return v, nil
+	}
+
+	return graphParams{math.NaN(), math.NaN()}.Get(name)
+}
+
+var seriesCall = regexp.MustCompile(`\b(summation|product)\(`)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// rewriteSeries turns summation(expr, k, a, b) and product(expr, k, a, b),
+// whose expr can't be evaluated before k is known, into calls of sumseries
+// and prodseries with the key of their body. They are named apart from sum,
+// which is the sum of a list however many arguments it has, and malformed
+// series are left for govaluate to report.
+func rewriteSeries(eq string) string {
+	var b strings.Builder
+	for {
+		loc := seriesCall.FindStringSubmatchIndex(eq)
+		if loc == nil {
+			b.WriteString(eq)
+			return b.String()
+		}
+
+		open := loc[1] - 1
+		end := matchingParen(eq, open)
+		args := splitTopLevel(eq[open+1:max(end, open+1)], ',')
+		if end < 0 || len(args) != 4 || !identifierPattern.MatchString(strings.TrimSpace(args[1])) {
+			b.WriteString(eq[:loc[1]])
+			eq = eq[loc[1]:]
+			continue
+		}
+
+		for i, a := range args {
+			args[i] = rewriteSeries(a)
+		}
+
+		key := strings.TrimSpace(args[1]) + ":" + args[0]
+		s, err := seriesBody(key)
+		if err != nil {
+			b.WriteString(eq[:loc[1]])
+			eq = eq[loc[1]:]
+			continue
+		}
+
+		name := "sumseries"
+		if eq[loc[2]:loc[3]] == "product" {
+			name = "prodseries"
+		}
+		fmt.Fprintf(&b, "%s%s(\"%s\", %s, %s", eq[:loc[0]], name, seriesKeyEscaper.Replace(key), strings.TrimSpace(args[2]), strings.TrimSpace(args[3]))
+		for _, v := range s.free {
+			fmt.Fprintf(&b, ", [%s]", v)
+		}
+		b.WriteString(")")
+
+		eq = eq[end+1:]
+	}
+}
+
+// matchingParen returns the index of the parenthesis closing the one at
+// open, or -1.
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// seriesBody returns the series of key, index:body, compiling it unless it
+// is cached.
+func seriesBody(key string) (*series, error) {
+	if s, ok := seriesBodies.get(key); ok {
+		return s, nil
+	}
+
+	index, src, _ := strings.Cut(key, ":")
+	body, err := govaluate.NewEvaluableExpressionWithFunctions(src, functions)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &series{body: body, index: index}
+	for _, v := range body.Vars() {
+		if v != index && !slices.Contains(s.free, v) {
+			s.free = append(s.free, v)
+		}
+	}
+
+	seriesBodies.put(key, s)
+
+	return s, nil
+}
+
+// newSeriesFunc evaluates a series rewritten by rewriteSeries, folding its
+// terms with op starting from identity.
+func newSeriesFunc(identity float64, op func(acc, term float64) float64) govaluate.ExpressionFunction {
+	return func(arguments ...interface{}) (interface{}, error) {
+		if len(arguments) < 3 {
+			return nil, fmt.Errorf("expected expr, k, a, b")
+		}
+		key, _ := arguments[0].(string)
+		a, _ := arguments[1].(float64)
+		b, _ := arguments[2].(float64)
+
+		s, err := seriesBody(key)
+		if err != nil {
+			return nil, err
+		}
+		params := boundParams{}
+		for i, v := range s.free {
+			if 3+i < len(arguments) {
+				params[v] = arguments[3+i]
+			}
+		}
+
+		a, b = math.Ceil(a), math.Floor(b)
+		if b-a >= maxTerms || math.IsNaN(a) || math.IsNaN(b) {
+			return math.NaN(), nil
+		}
+
+		acc := identity
+		for k := a; k <= b; k++ {
+			params[s.index] = k
+			acc = op(acc, evalFloat(s.body, params))
+		}
+
+		return acc, nil
+	}
+}
+
+func init() {
+	functions["sumseries"] = newSeriesFunc(0, func(acc, term float64) float64 { return acc + term })
+	functions["prodseries"] = newSeriesFunc(1, func(acc, term float64) float64 { return acc * term })
+}
+
+// recurrence is a sequence such as a(n) = a(n-1) + a(n-2), a(0)=0, a(1)=1,
+// plotted as points over the integers n on screen.
+type recurrence struct {
+	name, index string
+	body        *govaluate.EvaluableExpression
+	initial     map[int]float64
+
+	// first is the smallest index with an initial value, below which the
+	// sequence isn't defined.
+	first int
+
+	values *memo[int, float64]
+	depth  int
+}
+
+// recurrenceHead matches the left hand side of a recurrence, a(n).
+var recurrenceHead = regexp.MustCompile(`^\s*([A-Za-z_]\w*)\s*\(\s*([A-Za-z_]\w*)\s*\)\s*$`)
+
+// isRecurrence reports whether str defines a recurrence, that is a(n) = …
+// followed by initial values.
+func isRecurrence(str string) bool {
+	lhs, rhs, ok := strings.Cut(str, "=")
+	return ok && recurrenceHead.MatchString(lhs) && len(splitTopLevel(rhs, ',')) > 1
+}
+
+func parseRecurrence(str string) (*recurrence, error) {
+	lhs, rhs, _ := strings.Cut(str, "=")
+	m := recurrenceHead.FindStringSubmatch(lhs)
+	if m == nil {
+		return nil, fmt.Errorf("expected a(n) = …")
+	}
+
+	r := &recurrence{name: m[1], index: m[2], initial: make(map[int]float64), first: math.MaxInt, values: newMemo[int, float64](1 << 16)}
+
+	parts := splitTopLevel(rhs, ',')
+	for _, part := range parts[1:] {
+		lhs, v, ok := strings.Cut(part, "=")
+		lhs = strings.TrimSpace(lhs)
+		if !ok || !strings.HasPrefix(lhs, r.name+"(") || !strings.HasSuffix(lhs, ")") {
+			return nil, fmt.Errorf("expected initial values such as %s(0) = 1", r.name)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(lhs[len(r.name)+1 : len(lhs)-1]))
+		if err != nil {
+			return nil, err
+		}
+		e, err := govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(v), functions)
+		if err != nil {
+			return nil, err
+		}
+
+		r.initial[n] = evalFloat(e, graphParams{})
+		r.first = min(r.first, n)
+	}
+
+	fs := maps.Clone(functions)
+	fs[r.name] = func(arguments ...interface{}) (interface{}, error) {
+		if len(arguments) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", r.name)
+		}
+		n, _ := arguments[0].(float64)
+
+		return r.at(n), nil
+	}
+
+	var err error
+	r.body, err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(parts[0]), fs)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// at returns the nth term, computing the terms from the first up so that
+// the body only ever recurses into terms already in the memo.
+func (r *recurrence) at(x float64) float64 {
+	if x != math.Trunc(x) || x < float64(r.first) || x-float64(r.first) >= maxTerms {
+		return math.NaN()
+	}
+	n := int(x)
+
+	if v, ok := r.initial[n]; ok {
+		return v
+	}
+	if v, ok := r.values.get(n); ok {
+		return v
+	}
+
+	// a body going up, such as a(n+1), would never end
+	if r.depth > 100 {
+		return math.NaN()
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+
+	if r.depth == 1 {
+		i := n - 1
+		for i > r.first {
+			if _, ok := r.values.get(i); ok {
+				break
+			}
+			i--
+		}
+		for ; i < n; i++ {
+			if _, ok := r.values.get(i); !ok {
+				r.term(i)
+			}
+		}
+	}
+
+	return r.term(n)
+}
+
+func (r *recurrence) term(n int) float64 {
+	if v, ok := r.initial[n]; ok {
+		return v
+	}
+
+	v := evalFloat(r.body, boundParams{r.index: float64(n)})
+	r.values.put(n, v)
+
+	return v
+}
+
+func (r *recurrence) draw(c color.Color) {
+	left, _ := fromPixel(0, 0)
+	right, _ := fromPixel(float64(graph.Rect.Dx()), 0)
+
+	for n := math.Max(math.Ceil(left), float64(r.first)); n <= right; n++ {
+		if v := r.at(n); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			px, py := toPixel(n, v)
+			drawMarker(px, py, c)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+func newListFunc(g func([]float64) float64) govaluate.ExpressionFunction {
+	return func(arguments ...interface{}) (interface{}, error) {
+		l := listArgs(arguments)
+		if len(l) == 0 {
+			return nil, fmt.Errorf("expected a list")
+		}
+
+		return g(l), nil
+	}
+}
+
+// newNFloat64Func wraps g as a function of one number per name, with the
+// names used in the error message when the arguments don't match.
+func newNFloat64Func(g func(a []float64) float64, names ...string) govaluate.ExpressionFunction {
+	return func(arguments ...interface{}) (interface{}, error) {
+		if len(arguments) != len(names) {
+			return nil, fmt.Errorf("must have %d arguments: %s", len(names), strings.Join(names, ", "))
+		}
+
+		a := make([]float64, len(arguments))
+		for i, v := range arguments {
+			a[i], _ = v.(float64)
+		}
+
+		return g(a), nil
+	}
+}
+
+// newLocationScaleFunc wraps a standard distribution function g as
+// f(x), f(x, mu, sigma) and, for cdfs, f(lower, upper, mu, sigma).
+func newLocationScaleFunc(g func(x float64) float64, density bool) govaluate.ExpressionFunction {
+	return func(arguments ...interface{}) (interface{}, error) {
+		a := listArgs(arguments)
+		switch {
+		case len(a) == 1:
+			return g(a[0]), nil
+		case len(a) == 3 && density:
+			return g((a[0]-a[1])/a[2]) / a[2], nil
+		case len(a) == 3:
+			return g((a[0] - a[1]) / a[2]), nil
+		case len(a) == 4 && !density:
+			return g((a[1]-a[2])/a[3]) - g((a[0]-a[2])/a[3]), nil
+		case density:
+			return nil, fmt.Errorf("must have 1 or 3 arguments: x, mu, sigma")
+		}
+
+		return nil, fmt.Errorf("must have 1, 3 or 4 arguments: [lower,] x, mu, sigma")
+	}
+}
+
+func sum(l []float64) float64 {
+	var s float64
+	for _, v := range l {
+		s += v
+	}
+
+	return s
+}
+
+func mean(l []float64) float64 {
+	return sum(l) / float64(len(l))
+}
+
+// variance is the sample variance of l.
+func variance(l []float64) float64 {
+	if len(l) < 2 {
+		return math.NaN()
+	}
+
+	m := mean(l)
+	var s float64
+	for _, v := range l {
+		s += (v - m) * (v - m)
+	}
+
+	return s / float64(len(l)-1)
+}
+
+// quantile returns the p-quantile of l, interpolating linearly between
+// order statistics.
+func quantile(l []float64, p float64) float64 {
+	if p < 0 || p > 1 {
+		return math.NaN()
+	}
+
+	s := slices.Sorted(slices.Values(l))
+	h := p * float64(len(s)-1)
+	i := int(h)
+	if i+1 >= len(s) {
+		return s[len(s)-1]
+	}
+
+	return s[i] + (h-float64(i))*(s[i+1]-s[i])
+}
+
+func lgamma(x float64) float64 {
+	v, _ := math.Lgamma(x)
+	return v
+}
+
+func beta(a, b float64) float64 {
+	return math.Exp(lgamma(a) + lgamma(b) - lgamma(a+b))
+}
+
+func factorial(n float64) float64 {
+	if n < 0 || n != math.Trunc(n) {
+		return math.NaN()
+	}
+
+	return math.Round(math.Gamma(n + 1))
+}
+
+func choose(n, k float64) float64 {
+	if k < 0 || k > n || n != math.Trunc(n) || k != math.Trunc(k) {
+		return 0
+	}
+
+	return math.Round(math.Exp(lgamma(n+1) - lgamma(k+1) - lgamma(n-k+1)))
+}
+
+func permutations(n, k float64) float64 {
+	if k < 0 || k > n || n != math.Trunc(n) || k != math.Trunc(k) {
+		return 0
+	}
+
+	return math.Round(math.Exp(lgamma(n+1) - lgamma(n-k+1)))
+}
+
+// gammaP is the regularized lower incomplete gamma function P(a, x).
+func gammaP(a, x float64) float64 {
+	switch {
+	case x < 0 || a <= 0:
+		return math.NaN()
+	case x == 0:
+		return 0
+	case x < a+1:
+		// series
+		term := 1 / a
+		s := term
+		for n := 1; n < 1000; n++ {
+			term *= x / (a + float64(n))
+			s += term
+			if math.Abs(term) < math.Abs(s)*1e-16 {
+				break
+			}
+		}
+
+		return s * math.Exp(-x+a*math.Log(x)-lgamma(a))
+	}
+
+	// continued fraction for Q(a, x), by Lentz's method
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 1000; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-16 {
+			break
+		}
+	}
+
+	return 1 - math.Exp(-x+a*math.Log(x)-lgamma(a))*h
+}
+
+// betaI is the regularized incomplete beta function I_x(a, b).
+func betaI(x, a, b float64) float64 {
+	switch {
+	case x < 0 || x > 1 || a <= 0 || b <= 0:
+		return math.NaN()
+	case x == 0 || x == 1:
+		return x
+	case x > (a+1)/(a+b+2):
+		return 1 - betaI(1-x, b, a)
+	}
+
+	front := math.Exp(a*math.Log(x)+b*math.Log(1-x)-lgamma(a)-lgamma(b)+lgamma(a+b)) / a
+
+	// continued fraction, by Lentz's method
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m < 1000; m++ {
+		num := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-16 {
+			break
+		}
+	}
+
+	return front * h
+}
+
+// invertCDF finds x such that cdf(x) = p by bisection, widening the
+// initial bracket [lo, hi] as needed.
+func invertCDF(cdf func(float64) float64, p, lo, hi float64) float64 {
+	if p <= 0 || p >= 1 {
+		return math.NaN()
+	}
+
+	for i := 0; cdf(lo) > p && i < 1000; i++ {
+		lo -= hi - lo
+	}
+	for i := 0; cdf(hi) < p && i < 1000; i++ {
+		hi += hi - lo
+	}
+
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if cdf(mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
+
+func normalPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normalCDF(x float64) float64 {
+	return math.Erfc(-x/math.Sqrt2) / 2
+}
+
+func binomialPDF(n, p, k float64) float64 {
+	if k < 0 || k > n || k != math.Trunc(k) {
+		return 0
+	}
+
+	return choose(n, k) * math.Pow(p, k) * math.Pow(1-p, n-k)
+}
+
+func binomialCDF(n, p, k float64) float64 {
+	k = math.Floor(k)
+	switch {
+	case k < 0:
+		return 0
+	case k >= n:
+		return 1
+	}
+
+	return betaI(1-p, n-k, k+1)
+}
+
+func poissonPDF(lambda, k float64) float64 {
+	if k < 0 || k != math.Trunc(k) {
+		return 0
+	}
+	if lambda == 0 {
+		// every draw is 0, where 0·log 0 would be NaN
+		if k == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	return math.Exp(k*math.Log(lambda) - lambda - lgamma(k+1))
+}
+
+func poissonCDF(lambda, k float64) float64 {
+	k = math.Floor(k)
+	if k < 0 {
+		return 0
+	}
+
+	return 1 - gammaP(k+1, lambda)
+}
+
+func tPDF(x, v float64) float64 {
+	return math.Exp(lgamma((v+1)/2)-lgamma(v/2)) / math.Sqrt(v*math.Pi) * math.Pow(1+x*x/v, -(v+1)/2)
+}
+
+func tCDF(x, v float64) float64 {
+	tail := betaI(v/(v+x*x), v/2, 0.5) / 2
+	if x > 0 {
+		return 1 - tail
+	}
+
+	return tail
+}
+
+func chiSquaredPDF(x, k float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x == 0 {
+		// the density at 0 is finite only for k = 2
+		switch {
+		case k < 2:
+			return math.Inf(1)
+		case k == 2:
+			return 0.5
+		}
+		return 0
+	}
+
+	return math.Exp((k/2-1)*math.Log(x) - x/2 - k/2*math.Ln2 - lgamma(k/2))
+}
+
+func chiSquaredCDF(x, k float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+
+	return gammaP(k/2, x/2)
+}
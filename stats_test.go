@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestStatsFunctions checks the statistics functions of expressions against
+// published reference values (Abramowitz and Stegun, and standard tables).
+func TestStatsFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []interface{}
+		want float64
+		tol  float64
+	}{
+		{"gamma", []interface{}{5.0}, 24, 1e-12},
+		{"gamma", []interface{}{0.5}, 1.7724538509055159, 1e-14},
+		{"lgamma", []interface{}{10.0}, 12.801827480081469, 1e-12},
+		{"lgamma", []interface{}{100.0}, 359.13420536957540, 1e-10},
+		{"erf", []interface{}{1.0}, 0.8427007929497149, 1e-15},
+		{"erf", []interface{}{0.5}, 0.5204998778130465, 1e-15},
+		{"erfc", []interface{}{1.0}, 0.15729920705028513, 1e-15},
+		{"erfc", []interface{}{3.0}, 2.209049699858544e-05, 1e-18},
+		{"beta", []interface{}{2.0, 3.0}, 1.0 / 12, 1e-14},
+		{"beta", []interface{}{0.5, 0.5}, math.Pi, 1e-13},
+		{"normalcdf", []interface{}{1.96}, 0.9750021048517795, 1e-14},
+		{"normalcdf", []interface{}{-1.0}, 0.15865525393145707, 1e-14},
+		{"normalcdf", []interface{}{-1.0, 1.0, 0.0, 1.0}, 0.6826894921370859, 1e-14},
+		{"tcdf", []interface{}{1.0, 1.0}, 0.75, 1e-12},
+		{"tcdf", []interface{}{2.2281388519649385, 10.0}, 0.975, 1e-10},
+		{"tcdf", []interface{}{-2.0, 5.0}, 0.05096973941492914, 1e-10},
+		{"chisqcdf", []interface{}{2.0, 2.0}, 0.6321205588285577, 1e-12},
+		{"chisqcdf", []interface{}{3.841458820694124, 1.0}, 0.95, 1e-10},
+		{"chisqcdf", []interface{}{18.307038053275146, 10.0}, 0.95, 1e-10},
+		{"chisqpdf", []interface{}{1.0, 2.0}, 0.5 * math.Exp(-0.5), 1e-14},
+		{"binompdf", []interface{}{10.0, 0.5, 5.0}, 0.24609375, 1e-14},
+		{"binompdf", []interface{}{20.0, 0.3, 6.0}, 0.19163898275344238, 1e-12},
+		{"poissonpdf", []interface{}{3.0, 2.0}, 0.22404180765538775, 1e-14},
+		{"poissonpdf", []interface{}{10.0, 10.0}, 0.12511003572113372, 1e-12},
+		{"nCr", []interface{}{10.0, 3.0}, 120, 0},
+		{"nCr", []interface{}{52.0, 5.0}, 2598960, 0},
+		{"nPr", []interface{}{10.0, 3.0}, 720, 0},
+		{"nPr", []interface{}{6.0, 6.0}, 720, 0},
+		{"invnorm", []interface{}{0.975}, 1.959963984540054, 1e-12},
+		{"invnorm", []interface{}{0.05, 100.0, 15.0}, 75.32719559572791, 1e-10},
+		{"invt", []interface{}{0.975, 10.0}, 2.2281388519649385, 1e-9},
+		{"invt", []interface{}{0.95, 1.0}, 6.313751514675043, 1e-8},
+		{"invchisq", []interface{}{0.95, 1.0}, 3.841458820694124, 1e-9},
+		{"invchisq", []interface{}{0.95, 10.0}, 18.307038053275146, 1e-9},
+		{"invchisq", []interface{}{0.01, 5.0}, 0.5542980767282772, 1e-9},
+		{"factorial", []interface{}{0.0}, 1, 0},
+		{"factorial", []interface{}{5.0}, 120, 0},
+		{"factorial", []interface{}{10.0}, 3628800, 0},
+		{"normalpdf", []interface{}{0.0}, 0.3989422804014327, 1e-15},
+		{"normalpdf", []interface{}{1.0}, 0.24197072451914337, 1e-15},
+		{"normalpdf", []interface{}{115.0, 100.0, 15.0}, 0.016131381634609556, 1e-15},
+		{"tpdf", []interface{}{0.0, 1.0}, 1 / math.Pi, 1e-14},
+		{"tpdf", []interface{}{0.0, 2.0}, 0.35355339059327373, 1e-14},
+		{"tpdf", []interface{}{2.0, 5.0}, 0.0650903103262164, 1e-12},
+		{"binomcdf", []interface{}{10.0, 0.5, 5.0}, 0.623046875, 1e-14},
+		{"binomcdf", []interface{}{20.0, 0.3, 6.0}, 0.608009812200924, 1e-12},
+		{"poissoncdf", []interface{}{3.0, 2.0}, 8.5 * math.Exp(-3), 1e-14},
+		{"poissoncdf", []interface{}{10.0, 10.0}, 0.5830397501929855, 1e-12},
+
+		// the sample statistics of 2, 4, 4, 4, 5, 5, 7, 9
+		{"sum", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 40, 0},
+		{"count", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 8, 0},
+		{"mean", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 5, 0},
+		{"median", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 4.5, 0},
+		{"median", []interface{}{3.0, 1.0, 2.0}, 2, 0},
+		{"var", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 32.0 / 7, 1e-14},
+		{"stdev", []interface{}{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 2.138089935299395, 1e-14},
+		{"quantile", []interface{}{0.25, 2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 4, 1e-15},
+		{"quantile", []interface{}{0.9, 2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 7.6, 1e-14},
+		{"quantile", []interface{}{1.0, 2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0}, 9, 0},
+
+		// edge values, where log space would give 0·(-Inf)
+		{"poissonpdf", []interface{}{0.0, 0.0}, 1, 0},
+		{"poissonpdf", []interface{}{0.0, 1.0}, 0, 0},
+		{"chisqpdf", []interface{}{0.0, 2.0}, 0.5, 0},
+		{"chisqpdf", []interface{}{0.0, 1.0}, math.Inf(1), 0},
+		{"chisqpdf", []interface{}{0.0, 3.0}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		v, err := functions[tt.name](tt.args...)
+		if err != nil {
+			t.Errorf("%s%v: %v", tt.name, tt.args, err)
+			continue
+		}
+
+		got := v.(float64)
+		if got != tt.want && !(math.Abs(got-tt.want) <= tt.tol) {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Knetic/govaluate"
+)
+
+type vec3 [3]float64
+
+func (a vec3) sub(b vec3) vec3 { return vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func (a vec3) dot(b vec3) float64 { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+
+func (a vec3) normalize() vec3 {
+	l := math.Sqrt(a.dot(a))
+	if l == 0 {
+		return a
+	}
+
+	return vec3{a[0] / l, a[1] / l, a[2] / l}
+}
+
+// surfaceParams resolves the variables of a 3D plot.
+type surfaceParams struct {
+	x, y, t float64
+}
+
+func (p surfaceParams) Get(name string) (interface{}, error) {
+	if name == "t" {
+		return p.t, nil
+	}
+
+	return graphParams{p.x, p.y}.Get(name)
+}
+
+func evalFloat(e *govaluate.EvaluableExpression, p govaluate.Parameters) float64 {
+	v, err := e.Eval(p)
+	if f, ok := v.(float64); ok && err == nil {
+		return f
+	}
+
+	return math.NaN()
+}
+
+// object3D is either a surface z = f(x, y) or a parametric curve
+// (x(t), y(t), z(t)).
+type object3D struct {
+	surface *govaluate.EvaluableExpression
+	curve   [3]*govaluate.EvaluableExpression
+	color   color.Color
+}
+
+// parseObject3D parses z = f(x, y) or (x(t), y(t), z(t)).
+func parseObject3D(str string) (*object3D, error) {
+	str = strings.TrimSpace(str)
+
+	if lhs, rhs, ok := strings.Cut(str, "="); ok && strings.TrimSpace(lhs) == "z" {
+		e, err := govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(rhs), functions)
+		if err != nil {
+			return nil, err
+		}
+
+		return &object3D{surface: e}, nil
+	}
+
+	if !strings.HasPrefix(str, "(") || !strings.HasSuffix(str, ")") {
+		return nil, fmt.Errorf("expected z = f(x, y) or (x(t), y(t), z(t))")
+	}
+
+	parts := splitTopLevel(str[1:len(str)-1], ',')
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("a curve has 3 coordinates")
+	}
+
+	o := &object3D{}
+	for i, part := range parts {
+		var err error
+		o.curve[i], err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(part), functions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// splitTopLevel splits s at every sep that isn't nested in parentheses or
+// brackets.
+func splitTopLevel(s string, sep rune) []string {
+	var parts []string
+	var depth, start int
+	for i, c := range s {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + len(string(c))
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// camera orbits the origin.
+type camera struct {
+	yaw, pitch, distance float64
+}
+
+// project maps a point to image coordinates and its depth, which is
+// negative for points behind the camera.
+func (c camera) project(p vec3, w, h int) (sx, sy, depth float64) {
+	cy, sy0 := math.Cos(c.yaw), math.Sin(c.yaw)
+	x1 := p[0]*cy - p[1]*sy0
+	y1 := p[0]*sy0 + p[1]*cy
+
+	cp, sp := math.Cos(c.pitch), math.Sin(c.pitch)
+	y2 := y1*cp - p[2]*sp
+	z2 := y1*sp + p[2]*cp
+
+	depth = y2 + c.distance
+	f := float64(min(w, h))
+	return float64(w)/2 + f*x1/depth, float64(h)/2 - f*z2/depth, depth
+}
+
+type shading int
+
+const (
+	shadingFlat shading = iota
+	shadingGouraud
+	shadingWireframe
+)
+
+// rasterizer draws depth tested triangles and lines into an image.
+type rasterizer struct {
+	img   *image.RGBA
+	depth []float64
+}
+
+func newRasterizer(w, h int) *rasterizer {
+	r := &rasterizer{
+		img:   image.NewRGBA(image.Rect(0, 0, w, h)),
+		depth: make([]float64, w*h),
+	}
+	for i := range r.depth {
+		r.depth[i] = math.Inf(1)
+	}
+
+	return r
+}
+
+func (r *rasterizer) plot(x, y int, z float64, c color.RGBA) {
+	b := r.img.Rect
+	if x < b.Min.X || y < b.Min.Y || x >= b.Max.X || y >= b.Max.Y {
+		return
+	}
+
+	i := y*b.Dx() + x
+	if z < r.depth[i] {
+		r.depth[i] = z
+		r.img.SetRGBA(x, y, c)
+	}
+}
+
+type vertex struct {
+	x, y, z float64
+	c       [3]float64
+}
+
+func (r *rasterizer) triangle(a, b, c vertex) {
+	area := (b.x-a.x)*(c.y-a.y) - (b.y-a.y)*(c.x-a.x)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+
+	bounds := r.img.Rect
+	minX := max(int(math.Floor(min(a.x, b.x, c.x))), bounds.Min.X)
+	maxX := min(int(math.Ceil(max(a.x, b.x, c.x))), bounds.Max.X-1)
+	minY := max(int(math.Floor(min(a.y, b.y, c.y))), bounds.Min.Y)
+	maxY := min(int(math.Ceil(max(a.y, b.y, c.y))), bounds.Max.Y-1)
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			w0 := ((b.x-px)*(c.y-py) - (b.y-py)*(c.x-px)) / area
+			w1 := ((c.x-px)*(a.y-py) - (c.y-py)*(a.x-px)) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			var col color.RGBA
+			col.A = 0xff
+			for k, p := range []*uint8{&col.R, &col.G, &col.B} {
+				*p = uint8(math.Min(255, w0*a.c[k]+w1*b.c[k]+w2*c.c[k]))
+			}
+			r.plot(x, y, w0*a.z+w1*b.z+w2*c.z, col)
+		}
+	}
+}
+
+func (r *rasterizer) line(a, b vertex, c color.RGBA) {
+	steps := math.Ceil(math.Max(math.Abs(b.x-a.x), math.Abs(b.y-a.y)))
+	if math.IsNaN(steps) || steps > 1e5 {
+		return
+	}
+
+	for i := 0.0; i <= steps; i++ {
+		t := i / math.Max(steps, 1)
+		// bias lines towards the camera so they win against the faces
+		// they lie on
+		r.plot(int(a.x+(b.x-a.x)*t), int(a.y+(b.y-a.y)*t), a.z+(b.z-a.z)*t-1e-3, c)
+	}
+}
+
+// heightColor maps t in [0, 1] from blue through green and yellow to red.
+func heightColor(t float64) [3]float64 {
+	c := hsl(0.66*(1-math.Max(0, math.Min(1, t))), 0.85, 0.5)
+	return [3]float64{float64(c.R >> 8), float64(c.G >> 8), float64(c.B >> 8)}
+}
+
+// scene3D is what the 3D page renders.
+type scene3D struct {
+	objects map[int]*object3D
+	camera  camera
+	shading shading
+
+	// extent is the half width of the x/y domain of surfaces and
+	// resolution the number of grid cells along each axis.
+	extent     float64
+	resolution int
+
+	tMin, tMax float64
+}
+
+var light = vec3{-0.4, -0.5, 0.75}.normalize()
+
+func (s *scene3D) render(w, h int) image.Image {
+	r := newRasterizer(w, h)
+
+	project := func(p vec3) vertex {
+		x, y, z := s.camera.project(p, w, h)
+		if z <= 0.05 {
+			return vertex{x: math.NaN()}
+		}
+
+		return vertex{x: x, y: y, z: z}
+	}
+
+	axes := []vec3{{s.extent, 0, 0}, {0, s.extent, 0}, {0, 0, s.extent}}
+	for _, a := range axes {
+		r.line(project(vec3{}), project(a), color.RGBA{0x80, 0x80, 0x80, 0xff})
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(s.objects)) {
+		o := s.objects[id]
+		if o.surface != nil {
+			s.renderSurface(r, o, project)
+		} else {
+			s.renderCurve(r, o, project)
+		}
+	}
+
+	return r.img
+}
+
+func (s *scene3D) renderSurface(r *rasterizer, o *object3D, project func(vec3) vertex) {
+	n := s.resolution
+	points := make([][]vec3, n+1)
+
+	minZ, maxZ := math.Inf(1), math.Inf(-1)
+	for i := range points {
+		points[i] = make([]vec3, n+1)
+		for j := range points[i] {
+			x := -s.extent + 2*s.extent*float64(i)/float64(n)
+			y := -s.extent + 2*s.extent*float64(j)/float64(n)
+			z := evalFloat(o.surface, surfaceParams{x: x, y: y})
+			points[i][j] = vec3{x, y, z}
+			if !math.IsNaN(z) && !math.IsInf(z, 0) {
+				minZ, maxZ = math.Min(minZ, z), math.Max(maxZ, z)
+			}
+		}
+	}
+	if maxZ <= minZ {
+		maxZ = minZ + 1
+	}
+
+	at := func(i, j int) vec3 {
+		return points[max(0, min(n, i))][max(0, min(n, j))]
+	}
+
+	intensity := func(normal vec3) float64 {
+		return 0.3 + 0.7*math.Abs(normal.normalize().dot(light))
+	}
+
+	shade := func(p vec3, k float64) [3]float64 {
+		c := heightColor((p[2] - minZ) / (maxZ - minZ))
+		return [3]float64{c[0] * k, c[1] * k, c[2] * k}
+	}
+
+	vertexAt := func(i, j int) vertex {
+		p := points[i][j]
+		v := project(p)
+		switch s.shading {
+		case shadingGouraud:
+			normal := at(i+1, j).sub(at(i-1, j)).cross(at(i, j+1).sub(at(i, j-1)))
+			v.c = shade(p, intensity(normal))
+		case shadingWireframe:
+			v.c = shade(p, 1)
+		}
+
+		return v
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			a, b, c, d := vertexAt(i, j), vertexAt(i+1, j), vertexAt(i+1, j+1), vertexAt(i, j+1)
+			if math.IsNaN(a.x+b.x+c.x+d.x) || math.IsNaN(a.z+b.z+c.z+d.z) {
+				continue
+			}
+
+			switch s.shading {
+			case shadingWireframe:
+				col := color.RGBA{uint8(a.c[0]), uint8(a.c[1]), uint8(a.c[2]), 0xff}
+				// all four edges, as the quads past the last row and column
+				// or skipped for NaN won't close them
+				r.line(a, b, col)
+				r.line(b, c, col)
+				r.line(c, d, col)
+				r.line(d, a, col)
+			case shadingFlat:
+				p := [4]vec3{points[i][j], points[i+1][j], points[i+1][j+1], points[i][j+1]}
+				normal := p[2].sub(p[0]).cross(p[3].sub(p[1]))
+				center := vec3{(p[0][0] + p[2][0]) / 2, (p[0][1] + p[2][1]) / 2, (p[0][2] + p[1][2] + p[2][2] + p[3][2]) / 4}
+				a.c = shade(center, intensity(normal))
+				b.c, c.c, d.c = a.c, a.c, a.c
+				fallthrough
+			default:
+				r.triangle(a, b, c)
+				r.triangle(a, c, d)
+			}
+		}
+	}
+}
+
+func (s *scene3D) renderCurve(r *rasterizer, o *object3D, project func(vec3) vertex) {
+	cr, cg, cb, _ := o.color.RGBA()
+	col := color.RGBA{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8), 0xff}
+
+	steps := 50 * s.resolution
+	var last vertex
+	for i := 0; i <= steps; i++ {
+		t := s.tMin + (s.tMax-s.tMin)*float64(i)/float64(steps)
+		params := surfaceParams{t: t}
+		v := project(vec3{evalFloat(o.curve[0], params), evalFloat(o.curve[1], params), evalFloat(o.curve[2], params)})
+
+		if i != 0 && !math.IsNaN(v.x+last.x+v.y+last.y) {
+			r.line(last, v, col)
+			r.line(vertex{x: last.x + 1, y: last.y, z: last.z}, vertex{x: v.x + 1, y: v.y, z: v.z}, col)
+		}
+		last = v
+	}
+}
+
+// orbitView shows a rendered scene, orbiting its camera when dragged and
+// zooming when scrolled.
+type orbitView struct {
+	widget.BaseWidget
+
+	img    *canvas.Image
+	scene  *scene3D
+	width  int
+	height int
+}
+
+func newOrbitView(scene *scene3D) *orbitView {
+	v := &orbitView{scene: scene, width: 800, height: 800}
+	v.img = canvas.NewImageFromImage(scene.render(v.width, v.height))
+	v.img.FillMode = canvas.ImageFillContain
+	v.ExtendBaseWidget(v)
+
+	return v
+}
+
+func (v *orbitView) Refresh() {
+	v.img.Image = v.scene.render(v.width, v.height)
+	v.img.Refresh()
+}
+
+func (v *orbitView) Dragged(e *fyne.DragEvent) {
+	v.scene.camera.yaw += float64(e.Dragged.DX) * 0.01
+	v.scene.camera.pitch = math.Max(-math.Pi/2, math.Min(math.Pi/2, v.scene.camera.pitch+float64(e.Dragged.DY)*0.01))
+	v.Refresh()
+}
+
+func (v *orbitView) DragEnd() {}
+
+func (v *orbitView) Scrolled(e *fyne.ScrollEvent) {
+	v.scene.camera.distance = math.Max(0.5, v.scene.camera.distance*math.Pow(0.999, float64(e.Scrolled.DY)))
+	v.Refresh()
+}
+
+func (v *orbitView) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(v.img)
+}
+
+func surfacePage() fyne.CanvasObject {
+	scene := &scene3D{
+		objects:    make(map[int]*object3D),
+		camera:     camera{yaw: 0.6, pitch: 0.5, distance: 15},
+		shading:    shadingGouraud,
+		extent:     5,
+		resolution: 60,
+		tMin:       0,
+		tMax:       4 * math.Pi,
+	}
+
+	view := newOrbitView(scene)
+
+	floatInput := func(v *float64) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(strconv.FormatFloat(*v, 'f', 2, 64))
+		e.OnSubmitted = func(s string) {
+			i, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return
+			}
+			*v = i
+			view.Refresh()
+		}
+
+		return e
+	}
+
+	resolutionInput := widget.NewEntry()
+	resolutionInput.SetText(strconv.Itoa(scene.resolution))
+	resolutionInput.OnSubmitted = func(s string) {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 {
+			return
+		}
+		scene.resolution = i
+		view.Refresh()
+	}
+
+	shadingSelect := widget.NewSelect([]string{"Flat", "Gouraud", "Wireframe"}, func(s string) {
+		scene.shading = map[string]shading{"Flat": shadingFlat, "Gouraud": shadingGouraud, "Wireframe": shadingWireframe}[s]
+		view.Refresh()
+	})
+	shadingSelect.SetSelected("Gouraud")
+
+	objList := container.NewVBox()
+	var lastID int
+
+	addButton := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		lastID++
+		id := lastID
+		c := palette[(id-1)%len(palette)]
+
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder("z = f(x, y) or (x(t), y(t), z(t))")
+		entry.OnSubmitted = func(s string) {
+			o, err := parseObject3D(s)
+			if err != nil {
+				return
+			}
+
+			o.color = c
+			scene.objects[id] = o
+			view.Refresh()
+		}
+
+		var row *fyne.Container
+		deleteButton := &widget.Button{
+			Icon:       theme.ContentRemoveIcon(),
+			Importance: widget.DangerImportance,
+			OnTapped: func() {
+				objList.Remove(row)
+				delete(scene.objects, id)
+				view.Refresh()
+			},
+		}
+
+		row = container.NewBorder(nil, nil, newSwatch(c, entry.MinSize().Height, nil), deleteButton, entry)
+		objList.Add(row)
+	})
+
+	zoomIn := widget.NewButtonWithIcon("", theme.ZoomInIcon(), func() {
+		scene.camera.distance = math.Max(0.5, scene.camera.distance/1.25)
+		view.Refresh()
+	})
+	zoomOut := widget.NewButtonWithIcon("", theme.ZoomOutIcon(), func() {
+		scene.camera.distance *= 1.25
+		view.Refresh()
+	})
+
+	controls := container.NewHBox(
+		widget.NewLabel("Extent"), floatInput(&scene.extent),
+		widget.NewLabel("Resolution"), resolutionInput,
+		widget.NewLabel("t"), floatInput(&scene.tMin), floatInput(&scene.tMax),
+		shadingSelect, zoomIn, zoomOut, addButton,
+	)
+
+	return container.NewBorder(container.NewVBox(controls, objList), nil, nil, nil, view)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// swatch is a round, tappable color sample.
+type swatch struct {
+	widget.BaseWidget
+
+	rect     *canvas.Rectangle
+	OnTapped func()
+}
+
+func newSwatch(c color.Color, size float32, tapped func()) *swatch {
+	rect := canvas.NewRectangle(c)
+	rect.CornerRadius = size / 2
+	rect.SetMinSize(fyne.NewSquareSize(size))
+
+	s := &swatch{rect: rect, OnTapped: tapped}
+	s.ExtendBaseWidget(s)
+
+	return s
+}
+
+func (s *swatch) SetColor(c color.Color) {
+	s.rect.FillColor = c
+	s.rect.Refresh()
+}
+
+func (s *swatch) Tapped(*fyne.PointEvent) {
+	if s.OnTapped != nil {
+		s.OnTapped()
+	}
+}
+
+func (s *swatch) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.rect)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// tileable is a noiseGenerator that can repeat itself every px along x and
+// py along y, in sample units, 0 meaning that it doesn't along that axis.
+// tile returns nil if it can't repeat over those periods.
+type tileable interface {
+	noiseGenerator
+	tile(px, py float64) noiseGenerator
+}
+
+// tile makes g repeat every px along x and py along y: exactly if it is
+// tileable, and by blending it with itself a period away otherwise.
+func tile(g noiseGenerator, px, py float64) noiseGenerator {
+	if px == 0 && py == 0 {
+		return g
+	}
+	if t, ok := g.(tileable); ok {
+		if r := t.tile(px, py); r != nil {
+			return r
+		}
+	}
+
+	return blendTile{g, px, py}
+}
+
+// wrap reduces lattice coordinate i modulo a period of p cells, 0 meaning
+// none.
+func wrap(i, p int64) int64 {
+	if p == 0 {
+		return i
+	}
+
+	return (i%p + p) % p
+}
+
+// latticePeriod rounds the periods of a noise over an integer lattice to
+// whole cells.
+func latticePeriod(px, py float64) [2]int64 {
+	return [2]int64{int64(math.Round(px)), int64(math.Round(py))}
+}
+
+func (n valueNoise) tile(px, py float64) noiseGenerator {
+	n.period = latticePeriod(px, py)
+	return n
+}
+
+func (n worleyNoise) tile(px, py float64) noiseGenerator {
+	n.period = latticePeriod(px, py)
+	return n
+}
+
+func (n whiteNoise) tile(px, py float64) noiseGenerator {
+	n.period = latticePeriod(px, py)
+	return n
+}
+
+// tile only succeeds over multiples of the size of the tile, over which
+// blue noise repeats anyway.
+func (n blueNoise) tile(px, py float64) noiseGenerator {
+	p := latticePeriod(px, py)
+	if p[0]%int64(n.size) != 0 || p[1]%int64(n.size) != 0 {
+		return nil
+	}
+
+	return n
+}
+
+// tile maps the plane onto a torus in 4D simplex noise, which unlike the
+// triangular lattice of OpenSimplex2 repeats over any period.
+func (n openSimplex2) tile(px, py float64) noiseGenerator {
+	return torusNoise{n.seed, px, py}
+}
+
+// torusNoise is 4D simplex noise over the Clifford torus: x and y each turn
+// around a circle of circumference px and py, so that distances along the
+// plane are kept.
+type torusNoise struct {
+	seed   uint64
+	px, py float64
+}
+
+func (n torusNoise) Noise2D(x, y float64) float64 {
+	circle := func(v, p float64) (float64, float64) {
+		if p == 0 {
+			return v, 0
+		}
+		a := 2 * math.Pi * v / p
+		r := p / (2 * math.Pi)
+		return r * math.Cos(a), r * math.Sin(a)
+	}
+	a, b := circle(x, n.px)
+	c, d := circle(y, n.py)
+
+	return simplex4D(n.seed, a, b, c, d)
+}
+
+const (
+	simplexSkew4   = 0.30901699437494745 // (√5 - 1) / 4
+	simplexUnskew4 = 0.13819660112501053 // (5 - √5) / 20
+	simplexScale4  = 27.0                // scales the sum to about [-1, 1]
+)
+
+// simplexGradients4 are the midpoints of the 32 edges of the 4D hypercube.
+var simplexGradients4 = func() [32][4]float64 {
+	var g [32][4]float64
+	k := 0
+	for zero := range 4 {
+		for signs := range 8 {
+			b := 0
+			for c := range 4 {
+				if c == zero {
+					continue
+				}
+				g[k][c] = 1
+				if signs>>b&1 == 1 {
+					g[k][c] = -1
+				}
+				b++
+			}
+			k++
+		}
+	}
+	return g
+}()
+
+// simplex4D is Gustavson's 4D simplex noise, with hash2 picking gradients.
+func simplex4D(seed uint64, x, y, z, w float64) float64 {
+	p := [4]float64{x, y, z, w}
+
+	s := (x + y + z + w) * simplexSkew4
+	var cell [4]int64
+	var d0 [4]float64
+	t := 0.0
+	for c := range 4 {
+		f := math.Floor(p[c] + s)
+		cell[c] = int64(f)
+		t += f
+	}
+	t *= simplexUnskew4
+	for c := range 4 {
+		d0[c] = p[c] - (float64(cell[c]) - t)
+	}
+
+	// the order of the coordinates picks the simplex the point is in
+	var rank [4]int
+	for a := range 4 {
+		for b := a + 1; b < 4; b++ {
+			if d0[a] > d0[b] {
+				rank[a]++
+			} else {
+				rank[b]++
+			}
+		}
+	}
+
+	value := 0.0
+	for corner := range 5 {
+		var offset [4]int64
+		var d [4]float64
+		for c := range 4 {
+			if rank[c] >= 4-corner {
+				offset[c] = 1
+			}
+			d[c] = d0[c] - float64(offset[c]) + float64(corner)*simplexUnskew4
+		}
+
+		a := 0.6 - d[0]*d[0] - d[1]*d[1] - d[2]*d[2] - d[3]*d[3]
+		if a <= 0 {
+			continue
+		}
+
+		h := hash2(hash2(seed, cell[0]+offset[0], cell[1]+offset[1]), cell[2]+offset[2], cell[3]+offset[3])
+		g := simplexGradients4[h%32]
+		value += a * a * a * a * (g[0]*d[0] + g[1]*d[1] + g[2]*d[2] + g[3]*d[3])
+	}
+
+	return simplexScale4 * value
+}
+
+// blendTile repeats noise that can't be made periodic by mixing it with
+// itself a period away, weighted by the position within the period, and
+// scaled back to the contrast the mix loses halfway.
+type blendTile struct {
+	base   noiseGenerator
+	px, py float64
+}
+
+func (b blendTile) Noise2D(x, y float64) float64 {
+	var u, v float64
+	if b.px > 0 {
+		x -= b.px * math.Floor(x/b.px)
+		u = x / b.px
+	}
+	if b.py > 0 {
+		y -= b.py * math.Floor(y/b.py)
+		v = y / b.py
+	}
+
+	n := (1-u)*(1-v)*b.base.Noise2D(x, y) +
+		u*(1-v)*b.base.Noise2D(x-b.px, y) +
+		(1-u)*v*b.base.Noise2D(x, y-b.py) +
+		u*v*b.base.Noise2D(x-b.px, y-b.py)
+
+	return n / math.Sqrt(((1-u)*(1-u)+u*u)*((1-v)*(1-v)+v*v))
+}
+
+// scaledNoise samples base at x·fx, y·fy.
+type scaledNoise struct {
+	base   noiseGenerator
+	fx, fy float64
+}
+
+func (s scaledNoise) Noise2D(x, y float64) float64 {
+	return s.base.Noise2D(x*s.fx, y*s.fy)
+}
+
+// tiledFrequency rounds frequency so that noise sampled at it repeats a
+// whole number of times over period p, returning the period of the noise.
+func tiledFrequency(p, frequency float64) (float64, float64) {
+	if p == 0 {
+		return frequency, 0
+	}
+
+	q := max(1, math.Round(p*frequency))
+	return q / p, q
+}
+
+// tile repeats each octave a whole number of times over the period, at
+// frequencies that are close to, but no longer exactly, powers of the
+// lacunarity.
+func (f fractalNoise) tile(px, py float64) noiseGenerator {
+	if f.mode == fractalNone || f.octaves < 1 {
+		return tile(f.base, px, py)
+	}
+
+	f.tiled = make([]scaledNoise, f.octaves)
+	for i := range f.octaves {
+		freq := math.Pow(f.lacunarity, float64(i))
+		fx, qx := tiledFrequency(px, freq)
+		fy, qy := tiledFrequency(py, freq)
+		f.tiled[i] = scaledNoise{tile(f.base, qx, qy), fx, fy}
+	}
+
+	return f
+}
+
+// tile repeats the warp field over the period too, which is then sampled
+// at a frequency close to, but no longer exactly, the warp frequency.
+func (d domainWarp) tile(px, py float64) noiseGenerator {
+	if d.amplitude == 0 || d.iterations < 1 {
+		return tile(d.base, px, py)
+	}
+
+	fx, qx := tiledFrequency(px, d.frequency)
+	fy, qy := tiledFrequency(py, d.frequency)
+	d.base = tile(d.base, px, py)
+	d.warp = scaledNoise{tile(d.warp, qx, qy), fx, fy}
+	d.frequency = 1
+
+	return d
+}
+
+// tilePreview draws src four times at half its size, 2×2, so that the seams
+// of noise that doesn't tile show in the middle.
+func tilePreview(src image.Image) image.Image {
+	b := src.Bounds()
+	dst := image.NewNRGBA64(b)
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			dst.Set(b.Min.X+x, b.Min.Y+y, src.At(b.Min.X+2*x%b.Dx(), b.Min.Y+2*y%b.Dy()))
+		}
+	}
+
+	return dst
+}
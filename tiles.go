@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// noiseTileSize is the width and height of the tiles a render is split into.
+const noiseTileSize = 64
+
+// renderNoise samples p into values, the width×height pixels of the Noise
+// tab row by row, split into tiles rendered by a worker per CPU. Each tile
+// is sent on the returned channel once rendered, and the channel is closed
+// when every tile is, or when ctx is cancelled.
+func renderNoise(ctx context.Context, p noiseGenerator, values []float64, width, height int, xDivide, zDivide, intensify float64) <-chan image.Rectangle {
+	tiles := make(chan image.Rectangle)
+	done := make(chan image.Rectangle)
+
+	go func() {
+		defer close(tiles)
+		for y := 0; y < height; y += noiseTileSize {
+			for x := 0; x < width; x += noiseTileSize {
+				r := image.Rect(x, y, min(width, x+noiseTileSize), min(height, y+noiseTileSize))
+				select {
+				case tiles <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range tiles {
+				for y := r.Min.Y; y < r.Max.Y; y++ {
+					if ctx.Err() != nil {
+						return
+					}
+					for x := r.Min.X; x < r.Max.X; x++ {
+						values[y*width+x] = p.Noise2D(float64(x)/xDivide, float64(y)/zDivide) * intensify
+					}
+				}
+
+				select {
+				case done <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return done
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unitsMode evaluates equations with quantities such as 3 m or 9.81 m/s^2,
+// checking their dimensions.
+var unitsMode = false
+
+// dimension holds the exponents of the SI base units, in the order of
+// baseUnits.
+type dimension [7]int
+
+var baseUnits = [7]string{"m", "kg", "s", "A", "K", "mol", "cd"}
+
+func (d dimension) add(e dimension, sign int) dimension {
+	for i := range d {
+		d[i] += sign * e[i]
+	}
+
+	return d
+}
+
+func (d dimension) dimensionless() bool {
+	return d == dimension{}
+}
+
+// namedDimensions are the derived units used to show dimensions.
+var namedDimensions = []struct {
+	name string
+	dim  dimension
+}{
+	{"N", dimension{1, 1, -2}},
+	{"J", dimension{2, 1, -2}},
+	{"W", dimension{2, 1, -3}},
+	{"Pa", dimension{-1, 1, -2}},
+	{"V", dimension{2, 1, -3, -1}},
+	{"C", dimension{0, 0, 1, 1}},
+}
+
+func (d dimension) String() string {
+	for _, n := range namedDimensions {
+		if n.dim == d {
+			return n.name
+		}
+	}
+
+	var num, den []string
+	for i, e := range d {
+		name := baseUnits[i]
+		switch {
+		case e == 1:
+			num = append(num, name)
+		case e > 1:
+			num = append(num, name+"^"+strconv.Itoa(e))
+		case e == -1:
+			den = append(den, name)
+		case e < -1:
+			den = append(den, name+"^"+strconv.Itoa(-e))
+		}
+	}
+
+	s := strings.Join(num, "·")
+	if s == "" {
+		s = "1"
+	}
+	if len(den) != 0 {
+		s += "/" + strings.Join(den, "·")
+	}
+
+	return s
+}
+
+// quantity is a value in SI base units with its dimension. Units are the
+// quantity of one of them, such as {0.001, m} for mm.
+type quantity struct {
+	value float64
+	dim   dimension
+}
+
+var units = map[string]quantity{
+	"m":    {1, dimension{1}},
+	"g":    {1e-3, dimension{0, 1}},
+	"s":    {1, dimension{0, 0, 1}},
+	"A":    {1, dimension{0, 0, 0, 1}},
+	"K":    {1, dimension{0, 0, 0, 0, 1}},
+	"mol":  {1, dimension{0, 0, 0, 0, 0, 1}},
+	"cd":   {1, dimension{0, 0, 0, 0, 0, 0, 1}},
+	"N":    {1, dimension{1, 1, -2}},
+	"J":    {1, dimension{2, 1, -2}},
+	"W":    {1, dimension{2, 1, -3}},
+	"Pa":   {1, dimension{-1, 1, -2}},
+	"Hz":   {1, dimension{0, 0, -1}},
+	"V":    {1, dimension{2, 1, -3, -1}},
+	"C":    {1, dimension{0, 0, 1, 1}},
+	"L":    {1e-3, dimension{3}},
+	"eV":   {1.602176634e-19, dimension{2, 1, -2}},
+	"min":  {60, dimension{0, 0, 1}},
+	"h":    {3600, dimension{0, 0, 1}},
+	"day":  {86400, dimension{0, 0, 1}},
+	"inch": {0.0254, dimension{1}},
+	"ft":   {0.3048, dimension{1}},
+	"mi":   {1609.344, dimension{1}},
+	"lb":   {0.45359237, dimension{0, 1}},
+	"bar":  {1e5, dimension{-1, 1, -2}},
+	"atm":  {101325, dimension{-1, 1, -2}},
+	"cal":  {4.184, dimension{2, 1, -2}},
+}
+
+// unitPrefixes apply to the units up to "eV" in units, so km or mA but
+// not kmin.
+var unitPrefixes = map[string]float64{
+	"G": 1e9, "M": 1e6, "k": 1e3, "c": 1e-2, "m": 1e-3, "µ": 1e-6, "u": 1e-6, "n": 1e-9,
+}
+
+var prefixable = map[string]bool{
+	"m": true, "g": true, "s": true, "A": true, "K": true, "mol": true, "cd": true, "N": true,
+	"J": true, "W": true, "Pa": true, "Hz": true, "V": true, "C": true, "L": true, "eV": true,
+}
+
+func lookupUnit(name string) (quantity, bool) {
+	if u, ok := units[name]; ok {
+		return u, true
+	}
+
+	for prefix, f := range unitPrefixes {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && prefixable[rest] {
+			u := units[rest]
+			return quantity{u.value * f, u.dim}, true
+		}
+	}
+
+	return quantity{}, false
+}
+
+// quantityFunc returns the value in SI units of an expression at x, y,
+// given in the units of their axes.
+type quantityFunc func(x, y float64) float64
+
+// quantityVars are the dimensioned variables of an expression, x and y
+// usually, with their units.
+type quantityVars map[string]struct {
+	index int
+	unit  quantity
+}
+
+// compileQuantity compiles n checking its dimensions, which are the same
+// whatever the values of the variables.
+func compileQuantity(n *node, vars quantityVars) (quantityFunc, dimension, error) {
+	args := make([]quantityFunc, len(n.args))
+	dims := make([]dimension, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if args[i], dims[i], err = compileQuantity(a, vars); err != nil {
+			return nil, dimension{}, err
+		}
+	}
+
+	constant := func(v float64, d dimension) (quantityFunc, dimension, error) {
+		return func(x, y float64) float64 { return v }, d, nil
+	}
+	same := func(op string) error {
+		if dims[0] != dims[1] {
+			return fmt.Errorf("can't %s %s and %s", op, dims[0], dims[1])
+		}
+		return nil
+	}
+	dimensionless := func(what string) error {
+		for _, d := range dims {
+			if !d.dimensionless() {
+				return fmt.Errorf("%s takes numbers without units, not %s", what, d)
+			}
+		}
+		return nil
+	}
+
+	switch n.op {
+	case "num":
+		return constant(n.value, dimension{})
+	case "var":
+		if v, ok := vars[n.name]; ok {
+			f := v.unit.value
+			if v.index == 0 {
+				return func(x, y float64) float64 { return x * f }, v.unit.dim, nil
+			}
+			return func(x, y float64) float64 { return y * f }, v.unit.dim, nil
+		}
+		if c, ok := complexConstants[n.name]; ok && imag(c) == 0 {
+			return constant(real(c), dimension{})
+		}
+		if u, ok := lookupUnit(n.name); ok {
+			return constant(u.value, u.dim)
+		}
+
+		return nil, dimension{}, fmt.Errorf("unknown variable or unit '%s'", n.name)
+	case "neg":
+		a := args[0]
+		return func(x, y float64) float64 { return -a(x, y) }, dims[0], nil
+	case "!":
+		if err := dimensionless("!"); err != nil {
+			return nil, dimension{}, err
+		}
+		a := args[0]
+		return func(x, y float64) float64 { return boolValue(a(x, y) == 0) }, dimension{}, nil
+	case "+", "-", "%", "<", "<=", ">", ">=", "==", "!=":
+		verb := map[string]string{"+": "add", "-": "subtract", "%": "take the remainder of"}[n.op]
+		if verb == "" {
+			verb = "compare"
+		}
+		if err := same(verb); err != nil {
+			return nil, dimension{}, err
+		}
+
+		a, b, op := args[0], args[1], scalarOperators[n.op]
+		d := dims[0]
+		if verb == "compare" {
+			d = dimension{}
+		}
+		return func(x, y float64) float64 { return op(a(x, y), b(x, y)) }, d, nil
+	case "&&", "||":
+		if err := dimensionless(n.op); err != nil {
+			return nil, dimension{}, err
+		}
+		a, b, op := args[0], args[1], scalarOperators[n.op]
+		return func(x, y float64) float64 { return op(a(x, y), b(x, y)) }, dimension{}, nil
+	case "*":
+		a, b := args[0], args[1]
+		return func(x, y float64) float64 { return a(x, y) * b(x, y) }, dims[0].add(dims[1], 1), nil
+	case "/":
+		a, b := args[0], args[1]
+		return func(x, y float64) float64 { return a(x, y) / b(x, y) }, dims[0].add(dims[1], -1), nil
+	case "^":
+		a, b := args[0], args[1]
+		if !dims[1].dimensionless() {
+			return nil, dimension{}, fmt.Errorf("can't raise to a power in %s", dims[1])
+		}
+		if dims[0].dimensionless() {
+			return func(x, y float64) float64 { return math.Pow(a(x, y), b(x, y)) }, dimension{}, nil
+		}
+
+		p, ok := constValue(n.args[1])
+		if !ok {
+			return nil, dimension{}, fmt.Errorf("powers of %s must be constant", dims[0])
+		}
+		d, err := scaleDimension(dims[0], p)
+		if err != nil {
+			return nil, dimension{}, err
+		}
+		return func(x, y float64) float64 { return math.Pow(a(x, y), p) }, d, nil
+	case "?":
+		if !dims[0].dimensionless() {
+			return nil, dimension{}, fmt.Errorf("a condition has no units, not %s", dims[0])
+		}
+		if dims[1] != dims[2] {
+			return nil, dimension{}, fmt.Errorf("both branches must be in the same units, not %s and %s", dims[1], dims[2])
+		}
+		c, a, b := args[0], args[1], args[2]
+		return func(x, y float64) float64 {
+			if c(x, y) != 0 {
+				return a(x, y)
+			}
+			return b(x, y)
+		}, dims[1], nil
+	case "call":
+		return compileQuantityCall(n.name, args, dims)
+	}
+
+	return nil, dimension{}, fmt.Errorf("'%s' isn't defined on quantities", n.op)
+}
+
+func compileQuantityCall(name string, args []quantityFunc, dims []dimension) (quantityFunc, dimension, error) {
+	switch name {
+	case "sqrt", "cbrt":
+		if len(args) != 1 {
+			break
+		}
+		p := map[string]float64{"sqrt": 0.5, "cbrt": 1.0 / 3}[name]
+		d, err := scaleDimension(dims[0], p)
+		if err != nil {
+			return nil, dimension{}, err
+		}
+		a := args[0]
+		return func(x, y float64) float64 { return math.Pow(a(x, y), p) }, d, nil
+	case "abs", "floor", "ceil":
+		if len(args) != 1 {
+			break
+		}
+		f := map[string]func(float64) float64{"abs": math.Abs, "floor": math.Floor, "ceil": math.Ceil}[name]
+		a := args[0]
+		return func(x, y float64) float64 { return f(a(x, y)) }, dims[0], nil
+	case "min", "max", "hypot":
+		if len(args) != 2 {
+			break
+		}
+		if dims[0] != dims[1] {
+			return nil, dimension{}, fmt.Errorf("%s takes values in the same units, not %s and %s", name, dims[0], dims[1])
+		}
+		f := map[string]func(a, b float64) float64{"min": math.Min, "max": math.Max, "hypot": math.Hypot}[name]
+		a, b := args[0], args[1]
+		return func(x, y float64) float64 { return f(a(x, y), b(x, y)) }, dims[0], nil
+	}
+
+	f, ok := functions[name]
+	if !ok {
+		return nil, dimension{}, fmt.Errorf("unknown function '%s'", name)
+	}
+	for _, d := range dims {
+		if !d.dimensionless() {
+			return nil, dimension{}, fmt.Errorf("%s takes numbers without units, not %s", name, d)
+		}
+	}
+
+	return func(x, y float64) float64 {
+		values := make([]interface{}, len(args))
+		for i, a := range args {
+			values[i] = a(x, y)
+		}
+		v, err := f(values...)
+		if r, ok := v.(float64); ok && err == nil {
+			return r
+		}
+		return math.NaN()
+	}, dimension{}, nil
+}
+
+// scaleDimension returns d to the power p, if it has whole exponents.
+func scaleDimension(d dimension, p float64) (dimension, error) {
+	var r dimension
+	for i, e := range d {
+		v := float64(e) * p
+		if v != math.Trunc(v) {
+			return dimension{}, fmt.Errorf("%s to the power %g isn't a unit", d, p)
+		}
+		r[i] = int(v)
+	}
+
+	return r, nil
+}
+
+// parseUnit parses a unit expression such as km/h.
+func parseUnit(s string) (quantity, error) {
+	n, err := parseExpr(s)
+	if err != nil {
+		return quantity{}, err
+	}
+	f, d, err := compileQuantity(n, nil)
+	if err != nil {
+		return quantity{}, err
+	}
+
+	return quantity{f(0, 0), d}, nil
+}
+
+// axisUnits are the names and units of the axes of an equation.
+type axisUnits struct {
+	x, y         string
+	xUnit, yUnit string
+}
+
+func (a *axisUnits) labels() (x, y string) {
+	x, y = a.x, a.y
+	if a.xUnit != "" {
+		x += " [" + a.xUnit + "]"
+	}
+	if a.yUnit != "" {
+		y += " [" + a.yUnit + "]"
+	}
+
+	return
+}
+
+var inKeyword = regexp.MustCompile(`\s+in\s+`)
+
+// parseUnitGraph parses y = f(x) where f has units, followed by the units
+// of the variables, as in h = 20 m - 9.81 m/s^2 t^2/2, t in s. The
+// dependent variable's unit can also be given after the expression, as in
+// v = 3 m/s in km/h, and otherwise is the SI unit of the expression.
+func parseUnitGraph(str string) (Graph, *axisUnits, error) {
+	parts := splitTopLevel(str, ',')
+	lhs, rhs, ok := strings.Cut(parts[0], "=")
+	lhs = strings.TrimSpace(lhs)
+	if !ok || !identifierPattern.MatchString(lhs) {
+		return nil, nil, fmt.Errorf("expected y = f(x)")
+	}
+
+	axes := &axisUnits{x: "x", y: lhs}
+	xUnit, yUnit := quantity{value: 1}, quantity{value: 1}
+	var yUnitDeclared bool
+
+	if expr, unit, ok := cutIn(rhs); ok {
+		var err error
+		if yUnit, err = parseUnit(unit); err != nil {
+			return nil, nil, err
+		}
+		rhs, axes.yUnit, yUnitDeclared = expr, strings.TrimSpace(unit), true
+	}
+
+	for _, decl := range parts[1:] {
+		name, unit, ok := cutIn(decl)
+		name = strings.TrimSpace(name)
+		if !ok || !identifierPattern.MatchString(name) {
+			return nil, nil, fmt.Errorf("expected declarations such as x in s")
+		}
+		u, err := parseUnit(unit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if name == lhs {
+			yUnit, axes.yUnit, yUnitDeclared = u, strings.TrimSpace(unit), true
+		} else {
+			xUnit, axes.x, axes.xUnit = u, name, strings.TrimSpace(unit)
+		}
+	}
+
+	n, err := parseExpr(rhs)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := quantityVars{axes.x: {0, xUnit}}
+	f, d, err := compileQuantity(n, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if yUnitDeclared && d != yUnit.dim {
+		return nil, nil, fmt.Errorf("%s is in %s, not in %s", lhs, d, axes.yUnit)
+	}
+	if !yUnitDeclared && !d.dimensionless() {
+		axes.yUnit = d.String()
+	}
+
+	return func(x, y float64) (x1, y1 []float64) {
+		return oneXandOneY(x, f(x, y)/yUnit.value)
+	}, axes, nil
+}
+
+// cutIn cuts s around the "in" keyword of conversions.
+func cutIn(s string) (before, after string, found bool) {
+	loc := inKeyword.FindStringIndex(s)
+	if loc == nil {
+		return s, "", false
+	}
+
+	return s[:loc[0]], s[loc[1]:], true
+}
+
+// drawAxisLabels names the axes at their ends, with their units.
+func drawAxisLabels(a *axisUnits) {
+	x, y := a.labels()
+	xWidth, yWidth := 7*len([]rune(x)), 7*len([]rune(y))
+
+	ox, oy := toPixel(0, 0)
+	ox = math.Max(4, math.Min(ox, float64(graph.Rect.Dx()-yWidth-8)))
+	oy = math.Max(20, math.Min(oy, float64(graph.Rect.Dy()-8)))
+
+	drawLabel(graph, graph.Rect.Dx()-xWidth-6, int(oy)-6, x)
+	drawLabel(graph, int(ox)+6, 16, y)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// vectorField plots (dx/dt, dy/dt) = (f(x, y), g(x, y)) as arrows, or
+// dy/dx = f(x, y) as slope segments, along with the trajectories started
+// by clicking on the graph.
+type vectorField struct {
+	dx, dy *govaluate.EvaluableExpression
+
+	// slope draws unit segments instead of arrows scaled by speed. It is
+	// the only style for dy/dx = f(x, y), where dx/dt is 1.
+	slope bool
+
+	// adaptive integrates with Dormand-Prince RK45 rather than RK4 at a
+	// fixed step of precision.
+	adaptive bool
+
+	// starts are the points the trajectories go through, integrated again
+	// on every draw so that they follow the view.
+	starts [][2]float64
+}
+
+// vectorFieldSpacing is the distance between arrows, in pixels.
+const vectorFieldSpacing = 40
+
+// isVectorField reports whether str is one of the equations parsed by
+// parseVectorField.
+func isVectorField(str string) bool {
+	lhs, _, _ := strings.Cut(str, "=")
+	lhs = strings.ReplaceAll(lhs, " ", "")
+
+	return lhs == "dy/dx" || lhs == "(dx/dt,dy/dt)"
+}
+
+// parseVectorField parses (dx/dt, dy/dt) = (f(x, y), g(x, y)) or
+// dy/dx = f(x, y).
+func parseVectorField(str string) (*vectorField, error) {
+	lhs, rhs, _ := strings.Cut(str, "=")
+	rhs = strings.TrimSpace(rhs)
+
+	if strings.ReplaceAll(lhs, " ", "") == "dy/dx" {
+		f, err := govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(rhs), functions)
+		if err != nil {
+			return nil, err
+		}
+
+		return &vectorField{dy: f, slope: true}, nil
+	}
+
+	if !strings.HasPrefix(rhs, "(") || !strings.HasSuffix(rhs, ")") {
+		return nil, fmt.Errorf("expected (dx/dt, dy/dt) = (f(x, y), g(x, y))")
+	}
+	parts := splitTopLevel(rhs[1:len(rhs)-1], ',')
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("a vector field has 2 components")
+	}
+
+	var e [2]*govaluate.EvaluableExpression
+	for i, part := range parts {
+		var err error
+		e[i], err = govaluate.NewEvaluableExpressionWithFunctions(prepareExpression(part), functions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &vectorField{dx: e[0], dy: e[1]}, nil
+}
+
+// at returns the derivative of the trajectories at x, y.
+func (v *vectorField) at(x, y float64) (dx, dy float64) {
+	dx = 1
+	if v.dx != nil {
+		dx = evalFloat(v.dx, graphParams{x, y})
+	}
+
+	return dx, evalFloat(v.dy, graphParams{x, y})
+}
+
+func (v *vectorField) draw(c color.Color) {
+	b := graph.Rect
+
+	type arrow struct{ x, y, dx, dy float64 }
+	var arrows []arrow
+	longest := 0.0
+	for py := b.Min.Y + vectorFieldSpacing/2; py < b.Max.Y; py += vectorFieldSpacing {
+		for px := b.Min.X + vectorFieldSpacing/2; px < b.Max.X; px += vectorFieldSpacing {
+			x, y := fromPixel(float64(px), float64(py))
+			dx, dy := v.at(x, y)
+			if math.IsNaN(dx) || math.IsNaN(dy) || math.IsInf(dx, 0) || math.IsInf(dy, 0) {
+				continue
+			}
+
+			arrows = append(arrows, arrow{float64(px), float64(py), dx, -dy})
+			longest = math.Max(longest, math.Hypot(dx, dy))
+		}
+	}
+
+	const maxLength = vectorFieldSpacing * 0.8
+	for _, a := range arrows {
+		n := math.Hypot(a.dx, a.dy)
+		if n == 0 {
+			drawMarker(a.x, a.y, c)
+			continue
+		}
+
+		ux, uy := a.dx/n, a.dy/n
+		if v.slope {
+			drawLine(a.x-ux*maxLength/2, a.y-uy*maxLength/2, a.x+ux*maxLength/2, a.y+uy*maxLength/2, c)
+			continue
+		}
+
+		l := maxLength * n / longest
+		drawArrow(a.x-ux*l/2, a.y-uy*l/2, a.x+ux*l/2, a.y+uy*l/2, c)
+	}
+
+	for _, s := range v.starts {
+		for _, dir := range []float64{1, -1} {
+			path := v.trajectory(s[0], s[1], dir)
+			for i := 1; i < len(path); i++ {
+				x0, y0 := toPixel(path[i-1][0], path[i-1][1])
+				x1, y1 := toPixel(path[i][0], path[i][1])
+				drawLine(x0, y0, x1, y1, c)
+			}
+		}
+
+		px, py := toPixel(s[0], s[1])
+		drawMarker(px, py, c)
+	}
+}
+
+// drawArrow draws an arrow between two image coordinates, pointing at the
+// second.
+func drawArrow(x0, y0, x1, y1 float64, c color.Color) {
+	l := math.Hypot(x1-x0, y1-y0)
+	if l == 0 {
+		return
+	}
+	drawLine(x0, y0, x1, y1, c)
+
+	ux, uy := (x1-x0)/l, (y1-y0)/l
+	head := math.Min(6, l/2)
+	drawLine(x1, y1, x1-head*(ux+uy/2), y1-head*(uy-ux/2), c)
+	drawLine(x1, y1, x1-head*(ux-uy/2), y1-head*(uy+ux/2), c)
+}
+
+// trajectory integrates the field from x, y forwards in time, or backwards
+// when dir is -1, until it leaves the view, stalls or blows up.
+func (v *vectorField) trajectory(x, y, dir float64) [][2]float64 {
+	const maxSteps = 20000
+
+	f := func(x, y float64) (float64, float64) {
+		dx, dy := v.at(x, y)
+		return dir * dx, dir * dy
+	}
+
+	// the view, with a margin so that trajectories leave it smoothly
+	left, top := fromPixel(-50, -50)
+	right, bottom := fromPixel(float64(graph.Rect.Dx()+50), float64(graph.Rect.Dy()+50))
+
+	// steps never move more than a couple of pixels, so that the
+	// trajectory is drawn smoothly
+	maxMove := 2 / scale
+
+	path := [][2]float64{{x, y}}
+	h := precision
+	for len(path) < maxSteps {
+		var nx, ny float64
+		if v.adaptive {
+			var ok bool
+			nx, ny, h, ok = rk45Step(f, x, y, h, maxMove)
+			if !ok {
+				break
+			}
+		} else {
+			nx, ny = rk4Step(f, x, y, h)
+		}
+
+		if math.IsNaN(nx) || math.IsNaN(ny) || math.Hypot(nx-x, ny-y) < 1e-9*maxMove {
+			break
+		}
+		x, y = nx, ny
+		path = append(path, [2]float64{x, y})
+
+		if x < left || x > right || y < bottom || y > top {
+			break
+		}
+	}
+
+	return path
+}
+
+func rk4Step(f func(x, y float64) (float64, float64), x, y, h float64) (float64, float64) {
+	k1x, k1y := f(x, y)
+	k2x, k2y := f(x+h/2*k1x, y+h/2*k1y)
+	k3x, k3y := f(x+h/2*k2x, y+h/2*k2y)
+	k4x, k4y := f(x+h*k3x, y+h*k3y)
+
+	return x + h/6*(k1x+2*k2x+2*k3x+k4x), y + h/6*(k1y+2*k2y+2*k3y+k4y)
+}
+
+// dormandPrince is the Butcher tableau of the Dormand-Prince method: the
+// stage coefficients, then the 5th and 4th order weights.
+var dormandPrince = struct {
+	a      [7][6]float64
+	b5, b4 [7]float64
+}{
+	a: [7][6]float64{
+		{},
+		{1.0 / 5},
+		{3.0 / 40, 9.0 / 40},
+		{44.0 / 45, -56.0 / 15, 32.0 / 9},
+		{19372.0 / 6561, -25360.0 / 2187, 64448.0 / 6561, -212.0 / 729},
+		{9017.0 / 3168, -355.0 / 33, 46732.0 / 5247, 49.0 / 176, -5103.0 / 18656},
+		{35.0 / 384, 0, 500.0 / 1113, 125.0 / 192, -2187.0 / 6784, 11.0 / 84},
+	},
+	b5: [7]float64{35.0 / 384, 0, 500.0 / 1113, 125.0 / 192, -2187.0 / 6784, 11.0 / 84, 0},
+	b4: [7]float64{5179.0 / 57600, 0, 7571.0 / 16695, 393.0 / 640, -92097.0 / 339200, 187.0 / 2100, 1.0 / 40},
+}
+
+// rk45Step takes an adaptive Dormand-Prince step from x, y starting with
+// step h, shrinking it until the error estimate is within tolerance and
+// the move is at most maxMove. It returns the new point and the step to
+// try next, or false when the step underflows.
+func rk45Step(f func(x, y float64) (float64, float64), x, y, h, maxMove float64) (nx, ny, next float64, ok bool) {
+	const tolerance = 1e-6
+
+	for h > 1e-12 {
+		var kx, ky [7]float64
+		for i := range kx {
+			sx, sy := x, y
+			for j := 0; j < i; j++ {
+				sx += h * dormandPrince.a[i][j] * kx[j]
+				sy += h * dormandPrince.a[i][j] * ky[j]
+			}
+			kx[i], ky[i] = f(sx, sy)
+		}
+
+		nx, ny = x, y
+		var ex, ey float64
+		for i := range kx {
+			nx += h * dormandPrince.b5[i] * kx[i]
+			ny += h * dormandPrince.b5[i] * ky[i]
+			ex += h * (dormandPrince.b5[i] - dormandPrince.b4[i]) * kx[i]
+			ey += h * (dormandPrince.b5[i] - dormandPrince.b4[i]) * ky[i]
+		}
+		if math.IsNaN(nx) || math.IsNaN(ny) {
+			return nx, ny, h, false
+		}
+
+		err := math.Hypot(ex, ey) / (tolerance * (1 + math.Hypot(x, y)))
+		move := math.Hypot(nx-x, ny-y)
+		if err <= 1 && move <= maxMove {
+			factor := 5.0
+			if err > 0 {
+				factor = math.Min(5, 0.9*math.Pow(err, -0.2))
+			}
+			if move > 0 {
+				factor = math.Min(factor, maxMove/move)
+			}
+
+			return nx, ny, h * factor, true
+		}
+
+		shrink := 0.5
+		if err > 1 {
+			shrink = math.Max(0.1, 0.9*math.Pow(err, -0.25))
+		}
+		if move > maxMove {
+			shrink = math.Min(shrink, 0.9*maxMove/move)
+		}
+		h *= shrink
+	}
+
+	return x, y, h, false
+}
@@ -0,0 +1,38 @@
+package main
+
+// domainWarp samples noise at coordinates offset by other noise, as in
+// Inigo Quilez's f(p + g(p + h(p))): each iteration offsets the point by
+// the warp field sampled at the point offset by the iteration before.
+type domainWarp struct {
+	base, warp noiseGenerator
+
+	// amplitude is how far the point is moved, in sample units, and
+	// frequency scales the coordinates the warp field is sampled at.
+	amplitude, frequency float64
+	iterations           int
+}
+
+// warpOffsets decorrelate the x and y components of each iteration, which
+// would otherwise move the point along the diagonal.
+var warpOffsets = [][4]float64{
+	{0, 0, 5.2, 1.3},
+	{1.7, 9.2, 8.3, 2.8},
+	{3.1, 7.4, 6.6, 0.9},
+	{9.5, 4.2, 2.4, 6.1},
+}
+
+func (d domainWarp) Noise2D(x, y float64) float64 {
+	if d.amplitude == 0 || d.iterations < 1 {
+		return d.base.Noise2D(x, y)
+	}
+
+	var qx, qy float64
+	for i := range d.iterations {
+		o := warpOffsets[i%len(warpOffsets)]
+		px, py := x+d.amplitude*qx, y+d.amplitude*qy
+		qx = d.warp.Noise2D(d.frequency*px+o[0], d.frequency*py+o[1])
+		qy = d.warp.Noise2D(d.frequency*px+o[2], d.frequency*py+o[3])
+	}
+
+	return d.base.Noise2D(x+d.amplitude*qx, y+d.amplitude*qy)
+}